@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/config"
+	"github.com/Danyarbrg/flashCards/internal/db"
+)
+
+func runBackup(cfg config.AppConfig, args []string) error {
+	if err := db.Open(cfg.DBPath, sqliteOptions(cfg)); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if len(args) > 0 {
+		if err := db.Backup(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Backed up to %s.\n", args[0])
+		return nil
+	}
+	backup, err := db.BackupToDir(cfg.BackupDir, cfg.BackupKeep)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Backed up to %s/%s (%d bytes).\n", cfg.BackupDir, backup.Name, backup.Size)
+	return nil
+}
+
+func runRestore(cfg config.AppConfig, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("restore needs a backup file\n\n%s", usage)
+	}
+	if err := db.Restore(args[0], cfg.DBPath); err != nil {
+		return err
+	}
+	fmt.Printf("Restored %s from %s; the previous database is %s.before-restore.\n", cfg.DBPath, args[0], cfg.DBPath)
+	return nil
+}
+
+// scheduleBackups writes a backup to BACKUP_DIR every BACKUP_INTERVAL.
+func scheduleBackups(cfg config.AppConfig) {
+	if cfg.BackupInterval == 0 {
+		return
+	}
+	if db.Dialect != db.SQLite {
+		log.Printf("BACKUP_INTERVAL is ignored: %v", db.ErrBackupUnsupported)
+		return
+	}
+	log.Printf("Backing up the database to %s every %s, keeping %d.", cfg.BackupDir, cfg.BackupInterval, cfg.BackupKeep)
+	go func() {
+		for range time.Tick(cfg.BackupInterval) {
+			backup, err := db.BackupToDir(cfg.BackupDir, cfg.BackupKeep)
+			if err != nil {
+				log.Printf("Scheduled backup failed: %v", err)
+				continue
+			}
+			log.Printf("Backed up the database to %s.", backup.Name)
+		}
+	}()
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/Danyarbrg/flashCards/internal/config"
+	"github.com/Danyarbrg/flashCards/internal/db"
+)
+
+const usage = `usage: flashcards [command]
+
+Without a command the server is started.
+
+Commands:
+  migrate status     list migrations and whether they are applied
+  migrate up         apply all pending migrations
+  migrate down [n]   revert the last n migrations (default 1)
+  backup [file]      back up the SQLite database to file, or to BACKUP_DIR
+  restore <file>     check a backup and replace the database with it
+                     (stop the server first)`
+
+func sqliteOptions(cfg config.AppConfig) db.SQLiteOptions {
+	return db.SQLiteOptions{
+		JournalMode: cfg.SQLiteJournalMode,
+		Synchronous: cfg.SQLiteSynchronous,
+		BusyTimeout: cfg.SQLiteBusyTimeout,
+		ForeignKeys: cfg.SQLiteForeignKeys,
+		ReadConns:   cfg.SQLiteReadConns,
+	}
+}
+
+// runCommand runs a maintenance command instead of the server.
+func runCommand(cfg config.AppConfig, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(cfg, args[1:])
+	case "backup":
+		return runBackup(cfg, args[1:])
+	case "restore":
+		return runRestore(cfg, args[1:])
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", args[0], usage)
+	}
+}
+
+func runMigrate(cfg config.AppConfig, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("migrate needs a subcommand\n\n%s", usage)
+	}
+	if err := db.Open(cfg.DBPath, sqliteOptions(cfg)); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "status":
+		status, err := db.MigrationsStatus()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, s := range status {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+	case "up":
+		n, err := db.MigrateUp()
+		fmt.Printf("Applied %d migration(s).\n", n)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		n, err := db.MigrateDown(steps)
+		fmt.Printf("Reverted %d migration(s).\n", n)
+		return err
+	default:
+		return fmt.Errorf("unknown migrate subcommand %q\n\n%s", args[0], usage)
+	}
+}
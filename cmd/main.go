@@ -2,20 +2,74 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/Danyarbrg/flashCards/internal/api"
+	"github.com/Danyarbrg/flashCards/internal/auth"
 	"github.com/Danyarbrg/flashCards/internal/config"
 	"github.com/Danyarbrg/flashCards/internal/db"
+	"github.com/Danyarbrg/flashCards/internal/dictionary"
+	"github.com/Danyarbrg/flashCards/internal/frequency"
+	"github.com/Danyarbrg/flashCards/internal/lemma"
+	"github.com/Danyarbrg/flashCards/internal/mail"
+	"github.com/Danyarbrg/flashCards/internal/models"
+	"github.com/Danyarbrg/flashCards/internal/oidc"
+	"github.com/Danyarbrg/flashCards/internal/translate"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
 	cfg := config.InitEnv()
-	if err := db.InitDB(cfg.DBPath); err != nil {
+	if len(os.Args) > 1 {
+		if err := runCommand(cfg, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := auth.InitKeyring(cfg.JWTKeysDir, cfg.JWTActiveKeyID, cfg.JWTSecret, cfg.AppBaseURL); err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
+	if err := db.InitDB(cfg.DBPath, sqliteOptions(cfg)); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
+	scheduleBackups(cfg)
+	users := models.NewSQLUserRepository(db.DB, db.Read)
+	if err := users.PromoteAdmins(cfg.AdminEmails); err != nil {
+		log.Fatalf("Failed to promote admins: %v", err)
+	}
+	if err := dictionary.InitDictionaries(cfg.DictionaryPaths); err != nil {
+		log.Fatalf("Failed to load dictionaries: %v", err)
+	}
+	if err := translate.InitTranslator(cfg.Translator, cfg.TranslatorURL, cfg.TranslatorAPIKey, cfg.TranslateSource, cfg.TranslateTarget); err != nil {
+		log.Fatalf("Failed to initialize translator: %v", err)
+	}
+	frequencyLists := models.NewSQLFrequencyRepository(db.DB, db.Read)
+	if err := frequency.LoadDir(frequencyLists, cfg.FrequencyListsPath); err != nil {
+		log.Fatalf("Failed to load frequency lists: %v", err)
+	}
+	if err := lemma.InitLemmatizer(cfg.Lemmatizer); err != nil {
+		log.Fatalf("Failed to initialize lemmatizer: %v", err)
+	}
+	smtpMailer := mail.SMTPMailer{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.MailFrom,
+	}
+	if err := mail.InitMailer(cfg.Mailer, smtpMailer, cfg.MailLogPath); err != nil {
+		log.Fatalf("Failed to initialize mailer: %v", err)
+	}
+	if err := oidc.InitProvider(cfg.OIDCIssuer, cfg.OIDCClientID, cfg.OIDCClientSecret, cfg.OIDCRedirectURL, cfg.OIDCScopes); err != nil {
+		log.Fatalf("Failed to initialize OIDC provider: %v", err)
+	}
 
-	router := api.SetupRouter()
+	cards := models.NewSQLCardRepository(db.DB, db.Read)
+	scheduleTrashPurge(cfg, cards)
+
+	handler := api.NewHandler(cards, users, frequencyLists)
+	router := api.SetupRouter(handler)
 	// Обслуживание статических файлов из папки public
 	router.Static("/public", "../public")
 
@@ -31,6 +85,10 @@ func main() {
 		c.File("../public/review.html")
 	})
 
+	router.GET("/reset-password", func(c *gin.Context) {
+		c.File("../public/reset.html")
+	})
+
 	if err := router.Run(":" + cfg.Port); err != nil {
 		log.Fatalf("Server failed to start: %v", err)
 	}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/config"
+	"github.com/Danyarbrg/flashCards/internal/models"
+)
+
+const trashPurgeInterval = time.Hour
+
+// scheduleTrashPurge permanently deletes cards that have been in the trash
+// for longer than TRASH_RETENTION, on startup and then every hour.
+func scheduleTrashPurge(cfg config.AppConfig, cards models.CardRepository) {
+	purge := func() {
+		n, err := cards.PurgeTrash(time.Now().Add(-cfg.TrashRetention))
+		if err != nil {
+			log.Printf("Failed to purge trash: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("Purged %d flashcard(s) from the trash.", n)
+		}
+	}
+	go func() {
+		purge()
+		for range time.Tick(trashPurgeInterval) {
+			purge()
+		}
+	}()
+}
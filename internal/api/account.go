@@ -0,0 +1,271 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/auth"
+	"github.com/Danyarbrg/flashCards/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) getSessions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	sessionID, _ := c.Get("session_id")
+
+	sessions, err := h.Users.GetActiveSessions(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read sessions: %v", err)})
+		return
+	}
+
+	type sessionView struct {
+		models.Session
+		Current bool `json:"current"`
+	}
+	views := make([]sessionView, 0, len(sessions))
+	for _, s := range sessions {
+		views = append(views, sessionView{Session: s, Current: s.ID == sessionID.(int)})
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+func (h *Handler) revokeSession(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	err = h.Users.RevokeSession(id, userID.(int))
+	if errors.Is(err, models.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to revoke session: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// revokeOtherSessions signs out everywhere except the current session.
+func (h *Handler) revokeOtherSessions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	sessionID, _ := c.Get("session_id")
+
+	n, err := h.Users.RevokeOtherSessions(userID.(int), sessionID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to revoke sessions: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Other sessions revoked", "revoked": n})
+}
+
+func (h *Handler) getAccessTokens(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	tokens, err := h.Users.GetAccessTokens(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read access tokens: %v", err)})
+		return
+	}
+	if tokens == nil {
+		tokens = []models.AccessToken{}
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// createAccessToken issues a personal access token. The plain token is only
+// returned here and cannot be retrieved again.
+func (h *Handler) createAccessToken(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var input struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+	if len(input.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one scope is required"})
+		return
+	}
+	for _, scope := range input.Scopes {
+		if !auth.ValidScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown scope %q", scope)})
+			return
+		}
+	}
+	if input.ExpiresInDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days must not be negative"})
+		return
+	}
+
+	var expiresAt *time.Time
+	if input.ExpiresInDays > 0 {
+		t := time.Now().UTC().AddDate(0, 0, input.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	secret, _, err := auth.NewOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	plain := auth.PersonalTokenPrefix + secret
+	token, err := h.Users.CreateAccessToken(userID.(int), input.Name, auth.HashToken(plain), input.Scopes, expiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create access token: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Access token created, copy it now as it will not be shown again",
+		"token":        plain,
+		"access_token": token,
+	})
+}
+
+func (h *Handler) revokeAccessToken(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	err = h.Users.RevokeAccessToken(id, userID.(int))
+	if errors.Is(err, models.ErrAccessTokenNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Access token not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to revoke access token: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Access token revoked"})
+}
+
+// changePassword requires the current password and signs out every other
+// session.
+func (h *Handler) changePassword(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	sessionID, _ := c.Get("session_id")
+	var input struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+	if input.NewPassword == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New password is required"})
+		return
+	}
+
+	if _, ok := h.checkPassword(c, userID.(int), input.CurrentPassword); !ok {
+		return
+	}
+	if err := h.Users.SetPassword(userID.(int), input.NewPassword); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to change password: %v", err)})
+		return
+	}
+	if _, err := h.Users.RevokeOtherSessions(userID.(int), sessionID.(int)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to revoke sessions: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
+
+// changeEmail sends a confirmation link to the new address. The email is
+// only changed once the link is opened.
+func (h *Handler) changeEmail(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var input struct {
+		Password string `json:"password"`
+		NewEmail string `json:"new_email"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+	input.NewEmail = strings.TrimSpace(input.NewEmail)
+	if err := models.ValidateEmail(input.NewEmail); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.checkPassword(c, userID.(int), input.Password)
+	if !ok {
+		return
+	}
+	if _, err := h.Users.GetByEmail(input.NewEmail); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already in use"})
+		return
+	}
+
+	err := h.sendUserToken(user.ID, input.NewEmail, models.TokenEmailChange, input.NewEmail,
+		"/auth/confirm-email", "Confirm your new email",
+		"Please confirm the new email address of your FlashCards account by opening this link:", verificationTokenTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to send email: %v", err)})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Confirmation link sent to the new address"})
+}
+
+// deleteAccount permanently removes the account and all of its data.
+func (h *Handler) deleteAccount(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var input struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	if _, ok := h.checkPassword(c, userID.(int), input.Password); !ok {
+		return
+	}
+	if err := h.Users.Delete(userID.(int)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete account: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted"})
+}
+
+// checkPassword re-authenticates the user for sensitive account changes.
+func (h *Handler) checkPassword(c *gin.Context, userID int, password string) (models.User, bool) {
+	user, err := h.Users.GetByID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read user: %v", err)})
+		return user, false
+	}
+	key := accountKey("password", strconv.Itoa(userID))
+	if !allowAttempt(c, accountLimiter, key) {
+		return user, false
+	}
+	if _, err := h.Users.Authenticate(user.Email, password); err != nil {
+		failAttempt(c, accountLimiter, key)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+		return user, false
+	}
+	accountLimiter.Reset(key)
+	return user, true
+}
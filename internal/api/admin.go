@@ -0,0 +1,251 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Danyarbrg/flashCards/internal/config"
+	"github.com/Danyarbrg/flashCards/internal/db"
+	"github.com/Danyarbrg/flashCards/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin only lets active admins through. It must run after
+// AuthMiddleware.
+func (h *Handler) RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+		user, err := h.Users.GetByID(userID.(int))
+		if err != nil || !user.IsAdmin() || user.Disabled {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// bootstrapAdmin gives the admin role to a new user listed in ADMIN_EMAILS.
+func (h *Handler) bootstrapAdmin(user models.User) models.User {
+	for _, email := range config.InitEnv().AdminEmails {
+		if strings.EqualFold(strings.TrimSpace(email), user.Email) {
+			if err := h.Users.SetRole(user.ID, models.RoleAdmin); err != nil {
+				log.Printf("Failed to promote admin: %v", err)
+				return user
+			}
+			user.Role = models.RoleAdmin
+			return user
+		}
+	}
+	return user
+}
+
+func (h *Handler) getStats(c *gin.Context) {
+	stats, err := h.Users.GetStats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+func (h *Handler) listUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	users, total, err := h.Users.ListUsers(c.Query("q"), limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read users: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"users": users,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// adminUserID parses the :id parameter, answering 400 if it is invalid.
+func adminUserID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// adminError answers with the status matching a models error.
+func adminError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, models.ErrInvalidRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrLastAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *Handler) getUserSummary(c *gin.Context) {
+	id, ok := adminUserID(c)
+	if !ok {
+		return
+	}
+	user, err := h.Users.GetUserSummary(id)
+	if err != nil {
+		adminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *Handler) setUserRole(c *gin.Context) {
+	id, ok := adminUserID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		Role string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	if err := h.Users.SetRole(id, input.Role); err != nil {
+		adminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated"})
+}
+
+func (h *Handler) disableUser(c *gin.Context) {
+	id, ok := adminUserID(c)
+	if !ok {
+		return
+	}
+	if userID, _ := c.Get("user_id"); userID.(int) == id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot disable your own account"})
+		return
+	}
+
+	if err := h.Users.DisableUser(id); err != nil {
+		adminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "User disabled"})
+}
+
+func (h *Handler) enableUser(c *gin.Context) {
+	id, ok := adminUserID(c)
+	if !ok {
+		return
+	}
+	if err := h.Users.EnableUser(id); err != nil {
+		adminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "User enabled"})
+}
+
+// adminResetPassword sets a new password when one is given, signs the user
+// out everywhere and revokes their access tokens; otherwise it emails the
+// user a reset link.
+func (h *Handler) adminResetPassword(c *gin.Context) {
+	id, ok := adminUserID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	user, err := h.Users.GetByID(id)
+	if err != nil {
+		adminError(c, err)
+		return
+	}
+
+	if input.Password == "" {
+		if err := h.sendPasswordResetEmail(user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to send email: %v", err)})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"message": "Password reset link sent"})
+		return
+	}
+
+	if err := h.Users.SetPassword(user.ID, input.Password); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to reset password: %v", err)})
+		return
+	}
+	if err := h.Users.RevokeAllSessions(user.ID); err != nil {
+		log.Printf("Failed to revoke sessions after password reset: %v", err)
+	}
+	if err := h.Users.RevokeAllAccessTokens(user.ID); err != nil {
+		log.Printf("Failed to revoke access tokens after password reset: %v", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
+
+// createBackup writes a snapshot of the database to BACKUP_DIR.
+func (h *Handler) createBackup(c *gin.Context) {
+	cfg := config.InitEnv()
+	backup, err := db.BackupToDir(cfg.BackupDir, cfg.BackupKeep)
+	if errors.Is(err, db.ErrBackupUnsupported) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, backup)
+}
+
+func (h *Handler) listBackups(c *gin.Context) {
+	backups, err := db.ListBackups(config.InitEnv().BackupDir)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if backups == nil {
+		backups = []db.BackupFile{}
+	}
+	c.JSON(http.StatusOK, gin.H{"backups": backups})
+}
+
+// downloadBackup sends a backup so that it can be kept off the server.
+func (h *Handler) downloadBackup(c *gin.Context) {
+	name := c.Param("name")
+	if !db.IsBackupName(name) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup not found"})
+		return
+	}
+	path := filepath.Join(config.InitEnv().BackupDir, name)
+	if _, err := os.Stat(path); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup not found"})
+		return
+	}
+	c.FileAttachment(path, name)
+}
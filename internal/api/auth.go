@@ -0,0 +1,150 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/auth"
+	"github.com/Danyarbrg/flashCards/internal/config"
+	"github.com/Danyarbrg/flashCards/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// startSession creates a session for the user and returns the token pair.
+func (h *Handler) startSession(c *gin.Context, userID int) (gin.H, error) {
+	cfg := config.InitEnv()
+	refreshToken, refreshHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	session, err := h.Users.CreateSession(userID, refreshHash, c.Request.UserAgent(), c.ClientIP(), cfg.RefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := auth.NewAccessToken(auth.Keys(), userID, session.ID, cfg.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"token":         accessToken,
+		"refresh_token": refreshToken,
+		"expires_in":    int(cfg.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// refreshToken exchanges a refresh token for a new token pair. The refresh
+// token is rotated on every use.
+func (h *Handler) refreshToken(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || input.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+
+	if !allowAttempt(c, nil, "") {
+		return
+	}
+
+	cfg := config.InitEnv()
+	newToken, newHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	session, err := h.Users.RotateSession(auth.HashToken(input.RefreshToken), newHash, cfg.RefreshTokenTTL)
+	if errors.Is(err, models.ErrSessionNotFound) || errors.Is(err, models.ErrRefreshTokenReused) {
+		failAttempt(c, nil, "")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+
+	accessToken, err := auth.NewAccessToken(auth.Keys(), session.UserID, session.ID, cfg.AccessTokenTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         accessToken,
+		"refresh_token": newToken,
+		"expires_in":    int(cfg.AccessTokenTTL.Seconds()),
+	})
+}
+
+func (h *Handler) logout(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	sessionID, _ := c.Get("session_id")
+
+	if err := h.Users.RevokeSession(sessionID.(int), userID.(int)); err != nil && !errors.Is(err, models.ErrSessionNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// authenticateAccessToken authenticates a request made with a personal
+// access token. The token's scopes are stored in the context.
+func (h *Handler) authenticateAccessToken(c *gin.Context, token string) {
+	t, err := h.Users.GetAccessTokenByHash(auth.HashToken(token))
+	if err != nil || !t.Active() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid, expired or revoked access token"})
+		c.Abort()
+		return
+	}
+
+	if t.LastUsedAt == nil || time.Since(*t.LastUsedAt) > time.Minute {
+		if err := h.Users.TouchAccessToken(t.ID); err != nil {
+			log.Printf("Failed to update access token: %v", err)
+		}
+	}
+
+	c.Set("user_id", t.UserID)
+	c.Set("access_token_id", t.ID)
+	c.Set("scopes", strings.Join(t.Scopes, ","))
+	c.Next()
+}
+
+// RequireScope rejects personal access tokens that were not granted scope.
+// Login sessions have access to everything.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, ok := c.Get("scopes")
+		if ok && !auth.HasScope(scopes.(string), scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Token lacks the %q scope", scope)})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession rejects requests that are not made with a login session.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("session_id"); !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint requires a login session"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// getJWKS publishes the public signing keys so other services can verify
+// our access tokens.
+func (h *Handler) getJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": auth.Keys().JWKS()})
+}
@@ -0,0 +1,332 @@
+package api_test
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/api"
+	"github.com/Danyarbrg/flashCards/internal/auth"
+	"github.com/Danyarbrg/flashCards/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// newCardRouter returns a router backed by the memory repositories and a
+// function that signs in a new user of them.
+func newCardRouter(t *testing.T) (*gin.Engine, func() string) {
+	t.Helper()
+	users := models.NewMemoryUserRepository()
+	router := api.SetupRouter(api.NewHandler(models.NewMemoryCardRepository(), users, models.NewMemoryFrequencyRepository()))
+	signIn := func() string {
+		t.Helper()
+		user, err := users.Register(fmt.Sprintf("user-%s@example.com", rand.Text()), "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		session, err := users.CreateSession(user.ID, rand.Text(), "", "", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := auth.NewAccessToken(auth.Keys(), user.ID, session.ID, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	return router, signIn
+}
+
+// createCard creates a card through the API and returns it.
+func createCard(t *testing.T, router http.Handler, token, word, tags string) models.Flashcard {
+	t.Helper()
+	w := do(t, router, http.MethodPost, "/cards", token, gin.H{"word": word, "meaning": word + " meaning", "tags": tags})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create %q: status %d: %s", word, w.Code, w.Body.String())
+	}
+	var resp struct {
+		Card models.Flashcard `json:"card"`
+	}
+	decode(t, w, &resp)
+	return resp.Card
+}
+
+// getCard reads a card through the API, returning the status and card.
+func getCard(t *testing.T, router http.Handler, token string, id int) (int, models.Flashcard) {
+	t.Helper()
+	w := do(t, router, http.MethodGet, fmt.Sprintf("/cards/%d", id), token, nil)
+	var card models.Flashcard
+	if w.Code == http.StatusOK {
+		decode(t, w, &card)
+	}
+	return w.Code, card
+}
+
+func TestCreateFlashcard(t *testing.T) {
+	router, signIn := newCardRouter(t)
+	token := signIn()
+
+	card := createCard(t, router, token, "house", "home")
+	if card.ID == 0 || card.Word != "house" || card.Version != 1 {
+		t.Errorf("created card = %+v", card)
+	}
+	if code, got := getCard(t, router, token, card.ID); code != http.StatusOK || got.Meaning != "house meaning" {
+		t.Errorf("get: status %d, card %+v", code, got)
+	}
+
+	tests := []struct {
+		name string
+		body interface{}
+		want int
+	}{
+		{"no meaning", gin.H{"word": "tree"}, http.StatusBadRequest},
+		{"invalid JSON", "word", http.StatusBadRequest},
+		{"same word and meaning", gin.H{"word": "house", "meaning": "house meaning"}, http.StatusConflict},
+		{"same word", gin.H{"word": "House", "meaning": "a building"}, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		if w := do(t, router, http.MethodPost, "/cards", token, tt.body); w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.want, w.Body.String())
+		}
+	}
+	w := do(t, router, http.MethodPost, "/cards?force=true", token, gin.H{"word": "House", "meaning": "a building"})
+	if w.Code != http.StatusCreated {
+		t.Errorf("forced duplicate: status %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := do(t, router, http.MethodPost, "/cards", "", gin.H{"word": "a", "meaning": "b"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("without a token: status %d", w.Code)
+	}
+}
+
+func TestUpdateFlashcard(t *testing.T) {
+	router, signIn := newCardRouter(t)
+	token := signIn()
+	card := createCard(t, router, token, "cat", "")
+	path := fmt.Sprintf("/cards/%d", card.ID)
+
+	body := gin.H{"word": "cat", "meaning": "a small animal", "tags": "pets"}
+	w := do(t, router, http.MethodPut, path, token, body, "If-Match", fmt.Sprintf(`"%d-1"`, card.ID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("update: status %d: %s", w.Code, w.Body.String())
+	}
+	if etag := w.Header().Get("ETag"); etag != fmt.Sprintf(`"%d-2"`, card.ID) {
+		t.Errorf("ETag after update = %s", etag)
+	}
+	if _, got := getCard(t, router, token, card.ID); got.Meaning != "a small animal" || got.Tags != "pets" {
+		t.Errorf("updated card = %+v", got)
+	}
+
+	// A client still holding version 1 must not overwrite version 2.
+	w = do(t, router, http.MethodPut, path, token, gin.H{"word": "cat", "meaning": "stale"}, "If-Match", fmt.Sprintf(`"%d-1"`, card.ID))
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale update: status %d: %s", w.Code, w.Body.String())
+	}
+	var conflict struct {
+		Card models.Flashcard `json:"card"`
+	}
+	decode(t, w, &conflict)
+	if conflict.Card.Meaning != "a small animal" || conflict.Card.Version != 2 {
+		t.Errorf("412 card = %+v", conflict.Card)
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		body  interface{}
+		want  int
+	}{
+		{"without If-Match", path, token, gin.H{"word": "cat", "meaning": "a pet"}, http.StatusOK},
+		{"no meaning", path, token, gin.H{"word": "cat"}, http.StatusBadRequest},
+		{"missing card", "/cards/9999", token, body, http.StatusNotFound},
+		{"invalid ID", "/cards/abc", token, body, http.StatusBadRequest},
+		{"another user's card", path, signIn(), body, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := do(t, router, http.MethodPut, tt.path, tt.token, tt.body); w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.want, w.Body.String())
+		}
+	}
+}
+
+func TestRevertFlashcard(t *testing.T) {
+	router, signIn := newCardRouter(t)
+	token := signIn()
+	card := createCard(t, router, token, "tree", "")
+	path := fmt.Sprintf("/cards/%d", card.ID)
+
+	if w := do(t, router, http.MethodPut, path, token, gin.H{"word": "tree", "meaning": "a tall plant"}); w.Code != http.StatusOK {
+		t.Fatalf("update: status %d: %s", w.Code, w.Body.String())
+	}
+	w := do(t, router, http.MethodGet, path+"/revisions", token, nil)
+	var revisions []models.CardRevision
+	decode(t, w, &revisions)
+	if len(revisions) != 2 {
+		t.Fatalf("revisions = %+v", revisions)
+	}
+
+	w = do(t, router, http.MethodPost, fmt.Sprintf("%s/revisions/%d/revert", path, revisions[1].ID), token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("revert: status %d: %s", w.Code, w.Body.String())
+	}
+	var reverted models.Flashcard
+	decode(t, w, &reverted)
+	if reverted.Meaning != card.Meaning || reverted.Version != 3 {
+		t.Errorf("reverted card = %+v", reverted)
+	}
+	if etag := w.Header().Get("ETag"); etag != fmt.Sprintf(`"%d-3"`, card.ID) {
+		t.Errorf("ETag after revert = %s", etag)
+	}
+
+	w = do(t, router, http.MethodPost, fmt.Sprintf("%s/revisions/%d/revert", path, revisions[1].ID+1000), token, nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("revert to a missing revision: status %d", w.Code)
+	}
+}
+
+func TestDeleteFlashcard(t *testing.T) {
+	router, signIn := newCardRouter(t)
+	token := signIn()
+	card := createCard(t, router, token, "dog", "")
+	path := fmt.Sprintf("/cards/%d", card.ID)
+
+	if w := do(t, router, http.MethodDelete, path, token, nil, "If-Match", `"0-0"`); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("delete with a wrong ETag: status %d", w.Code)
+	}
+	if w := do(t, router, http.MethodDelete, path, signIn(), nil); w.Code != http.StatusOK {
+		t.Errorf("delete of another user's card: status %d", w.Code)
+	}
+	if code, _ := getCard(t, router, token, card.ID); code != http.StatusOK {
+		t.Fatalf("another user deleted the card: status %d", code)
+	}
+
+	if w := do(t, router, http.MethodDelete, path, token, nil); w.Code != http.StatusOK {
+		t.Fatalf("delete: status %d: %s", w.Code, w.Body.String())
+	}
+	if code, _ := getCard(t, router, token, card.ID); code != http.StatusNotFound {
+		t.Errorf("deleted card: status %d", code)
+	}
+
+	w := do(t, router, http.MethodGet, "/cards/trash", token, nil)
+	var trash []models.Flashcard
+	decode(t, w, &trash)
+	if len(trash) != 1 || trash[0].ID != card.ID {
+		t.Errorf("trash = %+v", trash)
+	}
+
+	if w := do(t, router, http.MethodPost, fmt.Sprintf("/cards/trash/%d/restore", card.ID), token, nil); w.Code != http.StatusOK {
+		t.Fatalf("restore: status %d: %s", w.Code, w.Body.String())
+	}
+	if code, _ := getCard(t, router, token, card.ID); code != http.StatusOK {
+		t.Errorf("restored card: status %d", code)
+	}
+	if w := do(t, router, http.MethodPost, fmt.Sprintf("/cards/trash/%d/restore", card.ID), token, nil); w.Code != http.StatusNotFound {
+		t.Errorf("restoring a card that is not in the trash: status %d", w.Code)
+	}
+}
+
+func TestReviewFlashcard(t *testing.T) {
+	router, signIn := newCardRouter(t)
+	token := signIn()
+	card := createCard(t, router, token, "bird", "")
+
+	due := func() []models.Flashcard {
+		w := do(t, router, http.MethodGet, "/cards/due", token, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("due: status %d: %s", w.Code, w.Body.String())
+		}
+		var cards []models.Flashcard
+		decode(t, w, &cards)
+		return cards
+	}
+	if cards := due(); len(cards) != 1 || cards[0].ID != card.ID {
+		t.Fatalf("due before review = %+v", cards)
+	}
+
+	path := fmt.Sprintf("/cards/review/%d", card.ID)
+	if w := do(t, router, http.MethodPost, path, token, gin.H{"quality": 6}); w.Code != http.StatusBadRequest {
+		t.Errorf("quality 6: status %d", w.Code)
+	}
+	if w := do(t, router, http.MethodPost, "/cards/review/9999", token, gin.H{"quality": 5}); w.Code != http.StatusNotFound {
+		t.Errorf("missing card: status %d", w.Code)
+	}
+	if w := do(t, router, http.MethodPost, path, token, gin.H{"quality": 5}); w.Code != http.StatusOK {
+		t.Fatalf("review: status %d: %s", w.Code, w.Body.String())
+	}
+
+	_, got := getCard(t, router, token, card.ID)
+	if got.Repetitions != 1 || got.Version != 2 || !got.NextReview.After(time.Now()) {
+		t.Errorf("reviewed card = %+v", got)
+	}
+	if cards := due(); len(cards) != 0 {
+		t.Errorf("due after review = %+v", cards)
+	}
+}
+
+func TestListFlashcards(t *testing.T) {
+	router, signIn := newCardRouter(t)
+	token := signIn()
+	apple := createCard(t, router, token, "apple", "fruit")
+	bean := createCard(t, router, token, "bean", "vegetable")
+	cherry := createCard(t, router, token, "cherry", "fruit")
+
+	list := func(token, query string) []models.Flashcard {
+		t.Helper()
+		w := do(t, router, http.MethodGet, "/cards"+query, token, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("list %s: status %d: %s", query, w.Code, w.Body.String())
+		}
+		var cards []models.Flashcard
+		decode(t, w, &cards)
+		return cards
+	}
+
+	if cards := list(token, ""); len(cards) != 3 {
+		t.Errorf("all cards = %d", len(cards))
+	}
+	if cards := list(token, "?limit=2&page=2"); len(cards) != 1 {
+		t.Errorf("second page of 2 = %d cards", len(cards))
+	}
+	if cards := list(token, "?tag=fruit"); len(cards) != 2 || cards[0].ID != apple.ID || cards[1].ID != cherry.ID {
+		t.Errorf("cards tagged fruit = %+v", cards)
+	}
+	if w := do(t, router, http.MethodPost, fmt.Sprintf("/cards/review/%d", bean.ID), token, gin.H{"quality": 5}); w.Code != http.StatusOK {
+		t.Fatalf("review: status %d", w.Code)
+	}
+	if cards := list(token, "?sort=repetitions&order=desc&limit=1"); len(cards) != 1 || cards[0].ID != bean.ID {
+		t.Errorf("most reviewed card = %+v", cards)
+	}
+	if cards := list(signIn(), ""); len(cards) != 0 {
+		t.Errorf("another user sees %d cards", len(cards))
+	}
+}
+
+func TestBulkFlashcards(t *testing.T) {
+	router, signIn := newCardRouter(t)
+	token := signIn()
+	card := createCard(t, router, token, "leaf", "")
+
+	tests := []struct {
+		name string
+		body interface{}
+		want int
+	}{
+		{"move", gin.H{"operations": []gin.H{{"op": "move", "id": card.ID}}}, http.StatusBadRequest},
+		{"unknown op", gin.H{"operations": []gin.H{{"op": "copy", "id": card.ID}}}, http.StatusBadRequest},
+		{"stale version", gin.H{"operations": []gin.H{{"op": "suspend", "id": card.ID, "version": card.Version + 1}}}, http.StatusUnprocessableEntity},
+		{"suspend", gin.H{"operations": []gin.H{{"op": "suspend", "id": card.ID, "version": card.Version}}}, http.StatusOK},
+		{"duplicate", gin.H{"operations": []gin.H{{"op": "create", "word": "leaf", "meaning": "a page"}}}, http.StatusUnprocessableEntity},
+		{"exact duplicate, forced", gin.H{"operations": []gin.H{{"op": "create", "word": "leaf", "meaning": card.Meaning, "force": true}}}, http.StatusUnprocessableEntity},
+		{"duplicate, forced", gin.H{"operations": []gin.H{{"op": "create", "word": "leaf", "meaning": "a page", "force": true}}}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if w := do(t, router, http.MethodPost, "/cards/bulk", token, tt.body); w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.want, w.Body.String())
+		}
+	}
+	if _, got := getCard(t, router, token, card.ID); !got.Suspended || got.Version != card.Version+1 {
+		t.Errorf("card after bulk suspend = %+v", got)
+	}
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Danyarbrg/flashCards/internal/dictionary"
+	"github.com/Danyarbrg/flashCards/internal/lemma"
+	"github.com/Danyarbrg/flashCards/internal/models"
+	"github.com/Danyarbrg/flashCards/internal/translate"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) lookupWord(c *gin.Context) {
+	word := c.Query("word")
+	if word == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'word' is required"})
+		return
+	}
+
+	entries, err := dictionary.Lookup(word)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to look up word: %v", err)})
+		return
+	}
+	if len(entries) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Word not found in dictionaries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"word":    word,
+		"entries": entries,
+	})
+}
+
+func (h *Handler) suggestCardFields(c *gin.Context) {
+	word := c.Query("word")
+	example := c.Query("example")
+	if word == "" && example == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'word' or 'example' is required"})
+		return
+	}
+
+	suggestion, err := translate.Suggest(c.Request.Context(), word, example)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("Failed to get suggestions: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, suggestion)
+}
+
+// cardResult reports the outcome of creating one card in a batch.
+type cardResult struct {
+	Word   string            `json:"word"`
+	Status string            `json:"status"`
+	Card   *models.Flashcard `json:"card,omitempty"`
+	Error  string            `json:"error,omitempty"`
+
+	Duplicates []models.Duplicate `json:"duplicates,omitempty"`
+}
+
+const errNoMeaning = "Meaning is required and no suggestion was found"
+
+// createSuggestedCard creates a card unless the user already has the word or
+// a form of it, filling an empty meaning from dictionaries or the translator.
+func (h *Handler) createSuggestedCard(c *gin.Context, userID int, word, meaning, example, tags string) cardResult {
+	res := cardResult{Word: word}
+	card := models.Flashcard{
+		UserID:  userID,
+		Word:    strings.TrimSpace(word),
+		Meaning: meaning,
+		Example: example,
+		Tags:    tags,
+	}
+
+	duplicates, err := h.Cards.FindDuplicates(card.UserID, card.Word, lemma.Default())
+	if err != nil {
+		res.Status, res.Error = "error", err.Error()
+		return res
+	}
+	if len(duplicates) > 0 {
+		res.Status, res.Duplicates = "exists", duplicates
+		return res
+	}
+
+	if card.Meaning == "" && card.Word != "" {
+		s, err := translate.Suggest(c.Request.Context(), card.Word, "")
+		if err != nil {
+			res.Status, res.Error = "error", err.Error()
+			return res
+		}
+		card.Meaning = s.Meaning
+	}
+	if card.Meaning == "" {
+		res.Status, res.Error = "error", errNoMeaning
+		return res
+	}
+
+	if err := h.Cards.Create(&card); err != nil {
+		res.Status, res.Error = "error", err.Error()
+		return res
+	}
+	res.Status, res.Card = "created", &card
+	return res
+}
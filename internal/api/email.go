@@ -0,0 +1,226 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/auth"
+	"github.com/Danyarbrg/flashCards/internal/config"
+	"github.com/Danyarbrg/flashCards/internal/mail"
+	"github.com/Danyarbrg/flashCards/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	verificationTokenTTL  = 48 * time.Hour
+	passwordResetTokenTTL = time.Hour
+)
+
+// sendUserToken creates a single-use token and emails a link containing it.
+func (h *Handler) sendUserToken(userID int, to, purpose, data, path, subject, text string, ttl time.Duration) error {
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+	if err := h.Users.CreateUserToken(userID, purpose, hash, data, ttl); err != nil {
+		return err
+	}
+
+	link := config.InitEnv().AppBaseURL + path + "?token=" + url.QueryEscape(token)
+	return mail.Send(mail.Message{
+		To:      to,
+		Subject: subject,
+		Body:    fmt.Sprintf("%s\n\n%s\n\nThe link expires in %s. If you did not request this, ignore this email.", text, link, ttl),
+	})
+}
+
+func (h *Handler) sendVerificationEmail(user models.User) error {
+	return h.sendUserToken(user.ID, user.Email, models.TokenEmailVerification, user.Email,
+		"/auth/verify-email", "Confirm your email",
+		"Please confirm your FlashCards email address by opening this link:", verificationTokenTTL)
+}
+
+func (h *Handler) sendPasswordResetEmail(user models.User) error {
+	return h.sendUserToken(user.ID, user.Email, models.TokenPasswordReset, "",
+		"/reset-password", "Reset your password",
+		"Someone asked to reset the password of your FlashCards account. To choose a new password, open this link:",
+		passwordResetTokenTTL)
+}
+
+func (h *Handler) verifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+
+	t, err := h.Users.ConsumeUserToken(models.TokenEmailVerification, auth.HashToken(token))
+	if errors.Is(err, models.ErrInvalidUserToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to verify email: %v", err)})
+		return
+	}
+
+	if err := h.Users.MarkEmailVerified(t.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to verify email: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// resendVerification sends a new verification link. It does not require a
+// session, since unverified users may not be allowed to log in.
+func (h *Handler) resendVerification(c *gin.Context) {
+	var input struct {
+		Email string `json:"email"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	// Every request sends an email, so each one counts as an attempt.
+	key := accountKey("verify", input.Email)
+	if !allowAttempt(c, accountLimiter, key) {
+		return
+	}
+	accountLimiter.Fail(key)
+
+	user, err := h.Users.GetByEmail(input.Email)
+	if err == nil && !user.EmailVerified {
+		err = h.sendVerificationEmail(user)
+	}
+	if err != nil && !errors.Is(err, models.ErrUserNotFound) {
+		log.Printf("Failed to send verification email: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the account exists and is not verified, a new link has been sent"})
+}
+
+// forgotPassword emails a reset link. The response is the same whether or
+// not the account exists, so it cannot be used to probe for emails.
+func (h *Handler) forgotPassword(c *gin.Context) {
+	var input struct {
+		Email string `json:"email"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	key := accountKey("reset", input.Email)
+	if !allowAttempt(c, accountLimiter, key) {
+		return
+	}
+	accountLimiter.Fail(key)
+
+	user, err := h.Users.GetByEmail(input.Email)
+	if err == nil {
+		err = h.sendPasswordResetEmail(user)
+	}
+	if err != nil && !errors.Is(err, models.ErrUserNotFound) {
+		log.Printf("Failed to send password reset email: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the account exists, a reset link has been sent"})
+}
+
+// resetPassword sets a new password using a reset token and signs the user
+// out of every session.
+func (h *Handler) resetPassword(c *gin.Context) {
+	var input struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+	if input.Token == "" || input.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token and password are required"})
+		return
+	}
+
+	if !allowAttempt(c, nil, "") {
+		return
+	}
+	t, err := h.Users.ConsumeUserToken(models.TokenPasswordReset, auth.HashToken(input.Token))
+	if errors.Is(err, models.ErrInvalidUserToken) {
+		failAttempt(c, nil, "")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to reset password: %v", err)})
+		return
+	}
+
+	if err := h.Users.SetPassword(t.UserID, input.Password); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to reset password: %v", err)})
+		return
+	}
+	// Receiving the link proves ownership of the address as well.
+	if err := h.Users.MarkEmailVerified(t.UserID); err != nil {
+		log.Printf("Failed to mark email verified: %v", err)
+	}
+	if err := h.Users.RevokeAllSessions(t.UserID); err != nil {
+		log.Printf("Failed to revoke sessions after password reset: %v", err)
+	}
+	if err := h.Users.RevokeAllAccessTokens(t.UserID); err != nil {
+		log.Printf("Failed to revoke access tokens after password reset: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
+
+// confirmEmailChange applies an email change requested from the account
+// settings and notifies the previous address.
+func (h *Handler) confirmEmailChange(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+
+	t, err := h.Users.ConsumeUserToken(models.TokenEmailChange, auth.HashToken(token))
+	if errors.Is(err, models.ErrInvalidUserToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to change email: %v", err)})
+		return
+	}
+
+	user, err := h.Users.GetByID(t.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read user: %v", err)})
+		return
+	}
+	err = h.Users.ChangeEmail(t.UserID, t.Data)
+	if errors.Is(err, models.ErrEmailTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already in use"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to change email: %v", err)})
+		return
+	}
+
+	notice := mail.Message{
+		To:      user.Email,
+		Subject: "Your email address was changed",
+		Body:    fmt.Sprintf("The email address of your FlashCards account was changed to %s. If you did not do this, contact the administrator.", t.Data),
+	}
+	if err := mail.Send(notice); err != nil {
+		log.Printf("Failed to notify previous email address: %v", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Email changed"})
+}
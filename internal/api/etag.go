@@ -0,0 +1,70 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Danyarbrg/flashCards/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// cardETag is the entity tag of a card. It changes with the card's version.
+func cardETag(card models.Flashcard) string {
+	return fmt.Sprintf(`"%d-%d"`, card.ID, card.Version)
+}
+
+// etagMatches reports whether header, an If-Match or If-None-Match value,
+// is "*" or lists etag. If-Match uses the strong comparison, under which
+// weak tags never match; If-None-Match uses the weak one.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion checks the If-Match header of a request that changes a
+// card and returns the version the change must apply to, or 0 without the
+// header. If the card is missing or was changed, it writes the response
+// and returns false.
+func (h *Handler) ifMatchVersion(c *gin.Context, id, userID int) (int, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return 0, true
+	}
+
+	card, err := h.Cards.GetByID(id, userID)
+	if errors.Is(err, models.ErrCardNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flashcard not found"})
+		return 0, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read flashcard: %v", err)})
+		return 0, false
+	}
+	if !etagMatches(header, cardETag(card), false) {
+		h.preconditionFailed(c, id, userID)
+		return 0, false
+	}
+	return card.Version, true
+}
+
+// preconditionFailed answers 412 with the current card, so the client can
+// apply its change to it and retry with the new ETag.
+func (h *Handler) preconditionFailed(c *gin.Context, id, userID int) {
+	card, err := h.Cards.GetByID(id, userID)
+	if err != nil {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Flashcard was changed since it was read"})
+		return
+	}
+	c.Header("ETag", cardETag(card))
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Flashcard was changed since it was read", "card": card})
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Danyarbrg/flashCards/internal/frequency"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) getFrequencyLists(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	lists, err := h.Frequency.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read frequency lists: %v", err)})
+		return
+	}
+	known, err := h.Cards.GetUserWords(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read user words: %v", err)})
+		return
+	}
+
+	reports := make([]frequency.Report, 0, len(lists))
+	for _, list := range lists {
+		words, err := h.Frequency.Words(list.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read frequency list: %v", err)})
+			return
+		}
+		reports = append(reports, frequency.Coverage(list, words, known, 0))
+	}
+	c.JSON(http.StatusOK, reports)
+}
+
+func (h *Handler) getFrequencyCoverage(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	missing, _ := strconv.Atoi(c.DefaultQuery("missing", "50"))
+	if missing < 0 {
+		missing = 50
+	}
+
+	report, ok := h.frequencyReport(c, userID.(int), missing)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// createFrequencyCards creates cards for the next N most frequent words of
+// the list that the user does not have yet. Words without a meaning are
+// reported and skipped, so that they do not keep later calls from getting
+// further down the list.
+func (h *Handler) createFrequencyCards(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var input struct {
+		Count int    `json:"count"`
+		Tags  string `json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+	if input.Count < 1 || input.Count > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Count must be between 1 and 500"})
+		return
+	}
+
+	report, ok := h.frequencyReport(c, userID.(int), math.MaxInt)
+	if !ok {
+		return
+	}
+
+	results := make([]cardResult, 0, input.Count)
+	created := 0
+	for _, w := range report.Missing {
+		if created == input.Count {
+			break
+		}
+		res := h.createSuggestedCard(c, userID.(int), w.Word, "", "", input.Tags)
+		results = append(results, res)
+		if res.Status == "created" {
+			created++
+		}
+		// Other errors, such as an unreachable translator, would fail for
+		// the next words as well.
+		if res.Status == "error" && res.Error != errNoMeaning {
+			break
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"created": created, "results": results})
+}
+
+func (h *Handler) frequencyReport(c *gin.Context, userID, maxMissing int) (frequency.Report, bool) {
+	list, ok, err := h.Frequency.Get(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read frequency list: %v", err)})
+		return frequency.Report{}, false
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Frequency list not found"})
+		return frequency.Report{}, false
+	}
+
+	words, err := h.Frequency.Words(list.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read frequency list: %v", err)})
+		return frequency.Report{}, false
+	}
+	known, err := h.Cards.GetUserWords(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read user words: %v", err)})
+		return frequency.Report{}, false
+	}
+	return frequency.Coverage(list, words, known, maxMissing), true
+}
+
+var frequencyListName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// importFrequencyList loads an uploaded list, one word per line and most
+// frequent first, replacing the list of the same name. The language
+// defaults to the part of the name before the first "-".
+func (h *Handler) importFrequencyList(c *gin.Context) {
+	name := c.Param("name")
+	if !frequencyListName.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "List names may only contain a-z, 0-9, - and _"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxTextUpload)
+	language := c.PostForm("language")
+	if language == "" {
+		language, _, _ = strings.Cut(name, "-")
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A list file is required"})
+		return
+	}
+	f, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+
+	err = frequency.Import(h.Frequency, name, language, data)
+	if errors.Is(err, frequency.ErrInvalidList) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to import frequency list: %v", err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to import frequency list: %v", err)})
+		return
+	}
+	list, _, err := h.Frequency.Get(name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read frequency list: %v", err)})
+		return
+	}
+	c.JSON(http.StatusCreated, list)
+}
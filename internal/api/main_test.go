@@ -0,0 +1,79 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/auth"
+	"github.com/Danyarbrg/flashCards/internal/config"
+	"github.com/Danyarbrg/flashCards/internal/db"
+	"github.com/Danyarbrg/flashCards/internal/mail"
+	"github.com/gin-gonic/gin"
+)
+
+// TestMain sets up what the server sets up at startup: configuration,
+// signing keys, a mailer and a fresh SQLite database for the tests that use
+// the SQL repositories.
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	dir, err := os.MkdirTemp("", "flashcards-api")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	os.Setenv("JWT_SECRET", "test-secret")
+	os.Setenv("APP_BASE_URL", "http://app.test")
+	cfg := config.InitEnv()
+	if err := auth.InitKeyring("", "", cfg.JWTSecret, cfg.AppBaseURL); err != nil {
+		log.Fatal(err)
+	}
+	if err := mail.InitMailer("log", mail.SMTPMailer{}, filepath.Join(dir, "mail.log")); err != nil {
+		log.Fatal(err)
+	}
+	opts := db.SQLiteOptions{JournalMode: "WAL", BusyTimeout: 5 * time.Second, ForeignKeys: true, ReadConns: 4}
+	if err := db.InitDB(filepath.Join(dir, "test.db"), opts); err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	db.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// do sends a request with an optional JSON body and bearer token.
+func do(t *testing.T, router http.Handler, method, path, token string, body interface{}, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// decode parses the JSON response into v.
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("invalid JSON %q: %v", w.Body.String(), err)
+	}
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/auth"
+	"github.com/Danyarbrg/flashCards/internal/config"
+	"github.com/Danyarbrg/flashCards/internal/models"
+	"github.com/Danyarbrg/flashCards/internal/oidc"
+	"github.com/gin-gonic/gin"
+)
+
+var errUnverifiedIdentity = errors.New("the identity provider did not return a verified email address")
+
+const (
+	oidcStateCookie = "oidc_state"
+	oidcStateTTL    = 10 * time.Minute
+)
+
+// authProviders tells the login page which external providers are enabled.
+func (h *Handler) authProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"oidc": oidc.Default() != nil})
+}
+
+// oidcLogin redirects to the identity provider. State, nonce and the PKCE
+// verifier travel in a signed, short-lived cookie.
+func (h *Handler) oidcLogin(c *gin.Context) {
+	provider := oidc.Default()
+	if provider == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "OpenID Connect login is not configured"})
+		return
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate state"})
+		return
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate nonce"})
+		return
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate code verifier"})
+		return
+	}
+
+	cfg := config.InitEnv()
+	cookie, err := auth.NewLoginStateToken(auth.Keys(), auth.LoginState{State: state, Nonce: nonce, CodeVerifier: verifier}, oidcStateTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate state"})
+		return
+	}
+
+	redirect, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, challenge)
+	if err != nil {
+		log.Printf("OIDC login failed: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		return
+	}
+
+	setOIDCStateCookie(c, cfg, cookie, int(oidcStateTTL.Seconds()))
+	c.Redirect(http.StatusFound, redirect)
+}
+
+// oidcCallback finishes the login at the provider, links or creates the
+// local user and hands the usual tokens to the web app in the URL fragment.
+func (h *Handler) oidcCallback(c *gin.Context) {
+	provider := oidc.Default()
+	if provider == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "OpenID Connect login is not configured"})
+		return
+	}
+	cfg := config.InitEnv()
+
+	raw, _ := c.Cookie(oidcStateCookie)
+	setOIDCStateCookie(c, cfg, "", -1)
+	state, err := auth.ParseLoginStateToken(auth.Keys(), raw)
+	if err != nil || c.Query("state") != state.State {
+		oidcRedirect(c, cfg, url.Values{"error": {"Login session expired, please try again"}})
+		return
+	}
+	if errCode := c.Query("error"); errCode != "" {
+		oidcRedirect(c, cfg, url.Values{"error": {"Login was cancelled: " + errCode}})
+		return
+	}
+
+	identity, err := provider.Exchange(c.Request.Context(), c.Query("code"), state.CodeVerifier, state.Nonce)
+	if err != nil {
+		log.Printf("OIDC callback failed: %v", err)
+		oidcRedirect(c, cfg, url.Values{"error": {"Login with the identity provider failed"}})
+		return
+	}
+
+	user, err := h.userForIdentity(identity)
+	if err != nil {
+		if errors.Is(err, errUnverifiedIdentity) {
+			oidcRedirect(c, cfg, url.Values{"error": {"The identity provider did not return a verified email address"}})
+			return
+		}
+		log.Printf("OIDC user lookup failed: %v", err)
+		oidcRedirect(c, cfg, url.Values{"error": {"Failed to sign in"}})
+		return
+	}
+
+	if user.Disabled {
+		oidcRedirect(c, cfg, url.Values{"error": {"Account is disabled"}})
+		return
+	}
+
+	tf, err := h.Users.GetTwoFactor(user.ID)
+	if err != nil {
+		oidcRedirect(c, cfg, url.Values{"error": {"Failed to read two-factor settings"}})
+		return
+	}
+	if tf.Enabled {
+		challenge, err := auth.NewChallengeToken(auth.Keys(), user.ID, challengeTokenTTL)
+		if err != nil {
+			oidcRedirect(c, cfg, url.Values{"error": {"Failed to generate token"}})
+			return
+		}
+		oidcRedirect(c, cfg, url.Values{"two_factor_required": {"true"}, "challenge_token": {challenge}})
+		return
+	}
+
+	tokens, err := h.startSession(c, user.ID)
+	if err != nil {
+		oidcRedirect(c, cfg, url.Values{"error": {"Failed to generate token"}})
+		return
+	}
+	oidcRedirect(c, cfg, url.Values{
+		"token":         {tokens["token"].(string)},
+		"refresh_token": {tokens["refresh_token"].(string)},
+		"expires_in":    {strconv.Itoa(tokens["expires_in"].(int))},
+	})
+}
+
+// userForIdentity finds the user linked to identity. Unlinked identities are
+// matched to users by email, which the provider must have verified.
+func (h *Handler) userForIdentity(identity oidc.Identity) (models.User, error) {
+	userID, err := h.Users.GetIdentityUserID(identity.Issuer, identity.Subject)
+	if err == nil {
+		return h.Users.GetByID(userID)
+	}
+	if !errors.Is(err, models.ErrUserNotFound) {
+		return models.User{}, err
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return models.User{}, errUnverifiedIdentity
+	}
+
+	user, err := h.Users.GetByEmail(identity.Email)
+	switch {
+	case errors.Is(err, models.ErrUserNotFound):
+		user, err = h.registerExternalUser(identity.Email)
+		if err != nil {
+			return user, err
+		}
+		user = h.bootstrapAdmin(user)
+	case err != nil:
+		return user, err
+	case !user.EmailVerified:
+		if err := h.claimUnverifiedUser(user.ID); err != nil {
+			return user, err
+		}
+	}
+
+	if err := h.Users.LinkIdentity(user.ID, identity.Issuer, identity.Subject, identity.Email); err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+// registerExternalUser creates a user whose email was verified by an
+// identity provider. The account gets a random password, so it can only
+// sign in through the provider until the password is reset.
+func (h *Handler) registerExternalUser(email string) (models.User, error) {
+	password, _, err := auth.NewOpaqueToken()
+	if err != nil {
+		return models.User{}, err
+	}
+	user, err := h.Users.Register(email, password)
+	if err != nil {
+		return user, err
+	}
+	if err := h.Users.MarkEmailVerified(user.ID); err != nil {
+		return user, err
+	}
+	user.EmailVerified = true
+	return user, nil
+}
+
+// claimUnverifiedUser takes over an account whose email was never verified
+// when the owner of that email signs in through a provider: the password
+// that somebody else may have set is replaced and everything they could
+// have set up with it is revoked.
+func (h *Handler) claimUnverifiedUser(userID int) error {
+	password, _, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+	return h.Users.ClaimUnverifiedUser(userID, password)
+}
+
+func setOIDCStateCookie(c *gin.Context, cfg config.AppConfig, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	secure := strings.HasPrefix(cfg.AppBaseURL, "https://")
+	c.SetCookie(oidcStateCookie, value, maxAge, "/auth/oidc", "", secure, true)
+}
+
+// oidcRedirect sends the browser back to the web app. Values go in the
+// fragment so they never reach server logs or Referer headers.
+func oidcRedirect(c *gin.Context, cfg config.AppConfig, values url.Values) {
+	c.Redirect(http.StatusFound, cfg.AppBaseURL+"/#"+values.Encode())
+}
@@ -0,0 +1,206 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/api"
+	"github.com/Danyarbrg/flashCards/internal/db"
+	"github.com/Danyarbrg/flashCards/internal/models"
+	"github.com/Danyarbrg/flashCards/internal/oidc"
+	"github.com/Danyarbrg/flashCards/internal/oidc/oidctest"
+	"github.com/gin-gonic/gin"
+)
+
+// newOIDCRouter starts a mock issuer and configures it as the provider.
+// The handler uses the SQL repositories, so sign-ins run against the schema.
+func newOIDCRouter(t *testing.T) (*gin.Engine, *oidctest.Issuer, models.UserRepository) {
+	t.Helper()
+	iss, err := oidctest.NewIssuer("flashcards")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(iss.Close)
+	if err := oidc.InitProvider(iss.URL, "flashcards", "", "http://app.test/auth/oidc/callback", nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { oidc.InitProvider("", "", "", "", nil) })
+
+	users := models.NewSQLUserRepository(db.DB, db.Read)
+	h := api.NewHandler(models.NewSQLCardRepository(db.DB, db.Read), users, models.NewSQLFrequencyRepository(db.DB, db.Read))
+	return api.SetupRouter(h), iss, users
+}
+
+// startOIDCLogin begins a login and returns the state cookie and the
+// issuer's redirect back to the callback.
+func startOIDCLogin(t *testing.T, router http.Handler, iss *oidctest.Issuer) (*http.Cookie, *url.URL) {
+	t.Helper()
+	w := do(t, router, http.MethodGet, "/auth/oidc/login", "", nil)
+	if w.Code != http.StatusFound {
+		t.Fatalf("login: status %d: %s", w.Code, w.Body.String())
+	}
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "oidc_state" {
+			cookie = c
+		}
+	}
+	if cookie == nil {
+		t.Fatal("login did not set the state cookie")
+	}
+	callback, err := iss.Authorize(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cookie, callback
+}
+
+// finishOIDCLogin calls the callback and returns the values the app gets
+// in the URL fragment.
+func finishOIDCLogin(t *testing.T, router http.Handler, cookie *http.Cookie, query url.Values) url.Values {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?"+query.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("callback: status %d: %s", w.Code, w.Body.String())
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := url.ParseQuery(location.Fragment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+func TestOIDCLoginCreatesUser(t *testing.T) {
+	router, iss, users := newOIDCRouter(t)
+	iss.Subject, iss.Email = "new-user", "oidc-new@example.com"
+
+	cookie, callback := startOIDCLogin(t, router, iss)
+	values := finishOIDCLogin(t, router, cookie, callback.Query())
+	if values.Get("token") == "" {
+		t.Fatalf("no token in %v", values)
+	}
+
+	user, err := users.GetByEmail("oidc-new@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.EmailVerified {
+		t.Error("user created from a verified identity is not verified")
+	}
+	w := do(t, router, http.MethodGet, "/cards", values.Get("token"), nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("token from OIDC login: status %d", w.Code)
+	}
+}
+
+func TestOIDCCallbackChecksState(t *testing.T) {
+	router, iss, _ := newOIDCRouter(t)
+	iss.Subject, iss.Email = "state-user", "oidc-state@example.com"
+
+	cookie, callback := startOIDCLogin(t, router, iss)
+	query := callback.Query()
+	query.Set("state", "forged")
+	values := finishOIDCLogin(t, router, cookie, query)
+	if values.Get("token") != "" || !strings.Contains(values.Get("error"), "expired") {
+		t.Errorf("forged state: got %v", values)
+	}
+
+	_, callback = startOIDCLogin(t, router, iss)
+	values = finishOIDCLogin(t, router, nil, callback.Query())
+	if values.Get("token") != "" || values.Get("error") == "" {
+		t.Errorf("missing state cookie: got %v", values)
+	}
+}
+
+func TestOIDCRejectsUnverifiedEmail(t *testing.T) {
+	router, iss, _ := newOIDCRouter(t)
+	iss.Subject, iss.Email, iss.EmailVerified = "unverified", "oidc-unverified@example.com", false
+
+	cookie, callback := startOIDCLogin(t, router, iss)
+	values := finishOIDCLogin(t, router, cookie, callback.Query())
+	if values.Get("token") != "" || !strings.Contains(values.Get("error"), "verified") {
+		t.Errorf("unverified identity: got %v", values)
+	}
+}
+
+// An account registered with somebody else's email is taken over by the
+// owner of the email, and what the squatter set up stops working.
+func TestOIDCClaimsUnverifiedUser(t *testing.T) {
+	router, iss, users := newOIDCRouter(t)
+	squatter, err := users.Register("oidc-claim@example.com", "squatter-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := users.CreateSession(squatter.ID, "claim-session", "", "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := users.CreateAccessToken(squatter.ID, "script", "claim-token", []string{"cards:read"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.EnableTwoFactor(squatter.ID, 1, []string{"code"}); err != nil {
+		t.Fatal(err)
+	}
+
+	iss.Subject, iss.Email = "owner", "oidc-claim@example.com"
+	cookie, callback := startOIDCLogin(t, router, iss)
+	values := finishOIDCLogin(t, router, cookie, callback.Query())
+	if values.Get("token") == "" {
+		t.Fatalf("owner was not signed in: %v", values)
+	}
+
+	if _, err := users.Authenticate("oidc-claim@example.com", "squatter-password"); err == nil {
+		t.Error("the squatter's password still works")
+	}
+	if s, err := users.GetSession(session.ID); err != nil || s.Active() {
+		t.Errorf("the squatter's session is still active (err %v)", err)
+	}
+	if tok, err := users.GetAccessTokenByHash("claim-token"); err != nil || tok.Active() || tok.ID != token.ID {
+		t.Errorf("the squatter's access token is still active (err %v)", err)
+	}
+	if tf, err := users.GetTwoFactor(squatter.ID); err != nil || tf.Enabled {
+		t.Errorf("the squatter's two-factor setup is still enabled (err %v)", err)
+	}
+	if user, err := users.GetByID(squatter.ID); err != nil || !user.EmailVerified {
+		t.Errorf("the claimed account is not verified (err %v)", err)
+	}
+}
+
+// Providers may return the email in another case than it was registered.
+func TestOIDCLinksEmailIgnoringCase(t *testing.T) {
+	router, iss, users := newOIDCRouter(t)
+	user, err := users.Register("oidc-case@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.MarkEmailVerified(user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	iss.Subject, iss.Email = "case", "OIDC-Case@Example.com"
+	cookie, callback := startOIDCLogin(t, router, iss)
+	values := finishOIDCLogin(t, router, cookie, callback.Query())
+	if values.Get("token") == "" {
+		t.Fatalf("user was not signed in: %v", values)
+	}
+	if userID, err := users.GetIdentityUserID(iss.URL, "case"); err != nil || userID != user.ID {
+		t.Errorf("identity linked to user %d, want %d (err %v)", userID, user.ID, err)
+	}
+	if _, err := users.Authenticate("oidc-case@example.com", "password"); err != nil {
+		t.Errorf("the account's password stopped working: %v", err)
+	}
+}
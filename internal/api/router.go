@@ -1,40 +1,141 @@
 package api
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Danyarbrg/flashCards/internal/auth"
 	"github.com/Danyarbrg/flashCards/internal/config"
+	"github.com/Danyarbrg/flashCards/internal/lemma"
 	"github.com/Danyarbrg/flashCards/internal/models"
+	"github.com/Danyarbrg/flashCards/internal/translate"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-func SetupRouter() *gin.Engine {
+// Handler serves the API. Cards and users are read and written through the
+// repositories so the storage can be swapped, e.g. for the in-memory ones in
+// tests.
+type Handler struct {
+	Cards     models.CardRepository
+	Users     models.UserRepository
+	Frequency models.FrequencyRepository
+}
+
+func NewHandler(cards models.CardRepository, users models.UserRepository, frequency models.FrequencyRepository) *Handler {
+	return &Handler{Cards: cards, Users: users, Frequency: frequency}
+}
+
+func SetupRouter(h *Handler) *gin.Engine {
 	r := gin.Default()
+	initThrottling(config.InitEnv())
+
+	r.POST("/register", h.register)
+	r.POST("/login", h.login)
+	r.POST("/auth/refresh", h.refreshToken)
+	r.POST("/auth/2fa", h.loginSecondFactor)
+	r.GET("/auth/verify-email", h.verifyEmail)
+	r.POST("/auth/resend-verification", h.resendVerification)
+	r.GET("/auth/confirm-email", h.confirmEmailChange)
+	r.POST("/auth/password/forgot", h.forgotPassword)
+	r.POST("/auth/password/reset", h.resetPassword)
+	r.GET("/auth/providers", h.authProviders)
+	r.GET("/auth/oidc/login", h.oidcLogin)
+	r.GET("/auth/oidc/callback", h.oidcCallback)
+	r.GET("/.well-known/jwks.json", h.getJWKS)
+
+	authGroup := r.Group("/auth")
+	authGroup.Use(h.AuthMiddleware(), RequireSession())
+	{
+		authGroup.POST("/logout", h.logout)
+	}
 
-	r.POST("/register", register)
-	r.POST("/login", login)
+	// Account management is only available to login sessions, never to
+	// personal access tokens.
+	account := r.Group("/account")
+	account.Use(h.AuthMiddleware(), RequireSession())
+	{
+		account.GET("/sessions", h.getSessions)
+		account.DELETE("/sessions", h.revokeOtherSessions)
+		account.DELETE("/sessions/:id", h.revokeSession)
+		account.GET("/tokens", h.getAccessTokens)
+		account.POST("/tokens", h.createAccessToken)
+		account.DELETE("/tokens/:id", h.revokeAccessToken)
+		account.POST("/password", h.changePassword)
+		account.POST("/email", h.changeEmail)
+		account.DELETE("", h.deleteAccount)
+		account.POST("/2fa/enroll", h.enrollTwoFactor)
+		account.POST("/2fa/confirm", h.confirmTwoFactor)
+		account.POST("/2fa/disable", h.disableTwoFactor)
+	}
+
+	admin := r.Group("/admin")
+	admin.Use(h.AuthMiddleware(), RequireSession(), h.RequireAdmin())
+	{
+		admin.GET("/stats", h.getStats)
+		admin.GET("/users", h.listUsers)
+		admin.GET("/users/:id", h.getUserSummary)
+		admin.PUT("/users/:id/role", h.setUserRole)
+		admin.POST("/users/:id/disable", h.disableUser)
+		admin.POST("/users/:id/enable", h.enableUser)
+		admin.POST("/users/:id/password", h.adminResetPassword)
+		admin.GET("/backups", h.listBackups)
+		admin.POST("/backups", h.createBackup)
+		admin.GET("/backups/:name", h.downloadBackup)
+		admin.PUT("/frequency-lists/:name", h.importFrequencyList)
+	}
+
+	read := RequireScope(auth.ScopeCardsRead)
+	write := RequireScope(auth.ScopeCardsWrite)
 
 	protected := r.Group("/cards")
-	protected.Use(AuthMiddleware())
+	protected.Use(h.AuthMiddleware())
 	{
-		protected.GET("", getFlashcards)
-		protected.POST("", createFlashcard)
-		protected.DELETE("/:id", deleteFlashcard)
-		protected.PUT("/:id", updateFlashcard)
-		protected.GET("/:id", getFlashcardByID)
-		protected.GET("/due", getDueFlashcards)
-		protected.POST("/review/:id", reviewFlashcard)
-		protected.GET("/tags", getAllUserTags)
+		protected.GET("", read, h.getFlashcards)
+		protected.POST("", write, h.createFlashcard)
+		protected.DELETE("/:id", write, h.deleteFlashcard)
+		protected.PUT("/:id", write, h.updateFlashcard)
+		protected.GET("/:id", read, h.getFlashcardByID)
+		protected.GET("/due", read, h.getDueFlashcards)
+		protected.POST("/review/:id", RequireScope(auth.ScopeReview), h.reviewFlashcard)
+		protected.GET("/tags", read, h.getAllUserTags)
+		protected.GET("/trash", read, h.getTrash)
+		protected.POST("/trash/:id/restore", write, h.restoreFlashcard)
+		protected.GET("/:id/revisions", read, h.getRevisions)
+		protected.POST("/:id/revisions/:revision/revert", write, h.revertFlashcard)
+		protected.GET("/suggest", read, h.suggestCardFields)
+		protected.POST("/bulk", write, h.bulkFlashcards)
+	}
+
+	dict := r.Group("/dictionary")
+	dict.Use(h.AuthMiddleware())
+	{
+		dict.GET("/lookup", read, h.lookupWord)
+	}
+
+	texts := r.Group("/texts")
+	texts.Use(h.AuthMiddleware())
+	{
+		texts.POST("", read, h.analyzeText)
+		texts.POST("/cards", write, h.createCardsFromText)
+	}
+
+	freq := r.Group("/frequency-lists")
+	freq.Use(h.AuthMiddleware())
+	{
+		freq.GET("", read, h.getFrequencyLists)
+		freq.GET("/:name", read, h.getFrequencyCoverage)
+		freq.POST("/:name/cards", write, h.createFrequencyCards)
 	}
 
 	return r
 }
 
-func AuthMiddleware() gin.HandlerFunc {
+func (h *Handler) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
@@ -47,40 +148,39 @@ func AuthMiddleware() gin.HandlerFunc {
 			tokenString = tokenString[7:]
 		}
 
-		cfg := config.InitEnv()
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(cfg.JWTSecret), nil
-		})
+		if strings.HasPrefix(tokenString, auth.PersonalTokenPrefix) {
+			h.authenticateAccessToken(c, tokenString)
+			return
+		}
 
-		if err != nil || !token.Valid {
+		claims, err := auth.ParseAccessToken(auth.Keys(), tokenString)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		session, err := h.Users.GetSession(claims.SessionID)
+		if err != nil || session.UserID != claims.UserID || !session.Active() {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
 			c.Abort()
 			return
 		}
 
-		userID, ok := claims["user_id"].(float64)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user_id in token"})
-			c.Abort()
-			return
+		// Keep last_used_at reasonably fresh without a write per request.
+		if time.Since(session.LastUsedAt) > time.Minute || session.IP != c.ClientIP() {
+			if err := h.Users.TouchSession(session.ID, c.ClientIP()); err != nil {
+				log.Printf("Failed to update session: %v", err)
+			}
 		}
 
-		c.Set("user_id", int(userID))
+		c.Set("user_id", claims.UserID)
+		c.Set("session_id", claims.SessionID)
 		c.Next()
 	}
 }
 
-func register(c *gin.Context) {
+func (h *Handler) register(c *gin.Context) {
 	var input struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
@@ -90,19 +190,30 @@ func register(c *gin.Context) {
 		return
 	}
 
-	user, err := models.RegisterUser(input.Email, input.Password)
+	ip := c.ClientIP()
+	if !allowAttempt(c, registerLimiter, ip) {
+		return
+	}
+	registerLimiter.Fail(ip)
+
+	user, err := h.Users.Register(input.Email, input.Password)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if err := h.sendVerificationEmail(user); err != nil {
+		log.Printf("Failed to send verification email: %v", err)
+	}
+	user = h.bootstrapAdmin(user)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "User registered",
 		"user":    user,
 	})
 }
 
-func login(c *gin.Context) {
+func (h *Handler) login(c *gin.Context) {
 	var input struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
@@ -112,31 +223,58 @@ func login(c *gin.Context) {
 		return
 	}
 
-	user, err := models.AuthenticateUser(input.Email, input.Password)
+	key := accountKey("login", input.Email)
+	if !allowAttempt(c, accountLimiter, key) {
+		return
+	}
+
+	user, err := h.Users.Authenticate(input.Email, input.Password)
 	if err != nil {
+		failAttempt(c, accountLimiter, key)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
+	accountLimiter.Reset(key)
 
-	cfg := config.InitEnv()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		return
+	}
+	if !user.EmailVerified && config.InitEnv().RequireEmailVerification {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Email address is not verified"})
+		return
+	}
 
-	tokenString, err := token.SignedString([]byte(cfg.JWTSecret))
+	tf, err := h.Users.GetTwoFactor(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read two-factor settings"})
+		return
+	}
+	if tf.Enabled {
+		challenge, err := auth.NewChallengeToken(auth.Keys(), user.ID, challengeTokenTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":             "Two-factor code required",
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+		})
+		return
+	}
+
+	tokens, err := h.startSession(c, user.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"token":   tokenString,
-	})
+	tokens["message"] = "Login successful"
+	c.JSON(http.StatusOK, tokens)
 }
 
-func getFlashcards(c *gin.Context) {
+func (h *Handler) getFlashcards(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	pageStr := c.DefaultQuery("page", "1")
 	limitStr := c.DefaultQuery("limit", "20")
@@ -154,7 +292,7 @@ func getFlashcards(c *gin.Context) {
 	}
 	offset := (page - 1) * limit
 
-	cards, err := models.GetSortedPaginated(userID.(int), limit, offset, sortBy, order, tag)
+	cards, err := h.Cards.List(userID.(int), limit, offset, sortBy, order, tag)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read flashcards: %v", err)})
 		return
@@ -162,7 +300,7 @@ func getFlashcards(c *gin.Context) {
 	c.JSON(http.StatusOK, cards)
 }
 
-func createFlashcard(c *gin.Context) {
+func (h *Handler) createFlashcard(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	var card models.Flashcard
 	if err := c.ShouldBindJSON(&card); err != nil {
@@ -170,34 +308,66 @@ func createFlashcard(c *gin.Context) {
 		return
 	}
 
+	// ?autofill=true fills an empty meaning from dictionaries or the
+	// translator and proposes a translation of the example.
+	var suggestion *translate.Suggestion
+	if c.Query("autofill") == "true" && card.Word != "" {
+		s, err := translate.Suggest(c.Request.Context(), card.Word, card.Example)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("Failed to get suggestions: %v", err)})
+			return
+		}
+		if card.Meaning == "" {
+			card.Meaning = s.Meaning
+		}
+		suggestion = &s
+	}
+
 	if card.Word == "" || card.Meaning == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Word and meaning are required"})
 		return
 	}
 
 	card.UserID = userID.(int)
-	exists, err := models.ExistsByWord(userID.(int), card.Word)
+	duplicates, err := h.Cards.FindDuplicates(userID.(int), card.Word, lemma.Default())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to check word existence: %v", err)})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to check for duplicates: %v", err)})
 		return
 	}
-	if exists {
-		c.JSON(http.StatusConflict, gin.H{"error": "Word already exists for this user"})
+	// ?force=true creates the card anyway, e.g. for a homonym with a
+	// different meaning. The very same word and meaning is never duplicated.
+	blocking, exact := models.BlockingDuplicates(duplicates, card.Meaning, c.Query("force") == "true")
+	if exact {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      "Card with this word and meaning already exists",
+			"duplicates": blocking,
+		})
+		return
+	}
+	if len(blocking) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      "Possible duplicates found, repeat with force=true to create anyway",
+			"duplicates": blocking,
+		})
 		return
 	}
 
-	if err := card.Save(); err != nil {
+	if err := h.Cards.Create(&card); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to save flashcard: %v", err)})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	resp := gin.H{
 		"message": "Flashcard created",
 		"card":    card,
-	})
+	}
+	if suggestion != nil {
+		resp["suggestions"] = suggestion
+	}
+	c.JSON(http.StatusCreated, resp)
 }
 
-func deleteFlashcard(c *gin.Context) {
+func (h *Handler) deleteFlashcard(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
@@ -206,15 +376,55 @@ func deleteFlashcard(c *gin.Context) {
 		return
 	}
 
-	if err := models.Delete(id, userID.(int)); err != nil {
+	version, ok := h.ifMatchVersion(c, id, userID.(int))
+	if !ok {
+		return
+	}
+
+	err = h.Cards.Delete(id, userID.(int), version)
+	if errors.Is(err, models.ErrVersionConflict) {
+		h.preconditionFailed(c, id, userID.(int))
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete flashcard: %v", err)})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Flashcard deleted"})
+	c.JSON(http.StatusOK, gin.H{"message": "Flashcard moved to trash"})
 }
 
-func updateFlashcard(c *gin.Context) {
+func (h *Handler) getTrash(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	cards, err := h.Cards.ListTrash(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read trash: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, cards)
+}
+
+func (h *Handler) restoreFlashcard(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	err = h.Cards.Restore(id, userID.(int))
+	if errors.Is(err, models.ErrCardNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flashcard not found in trash"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to restore flashcard: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Flashcard restored"})
+}
+
+func (h *Handler) updateFlashcard(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -239,15 +449,109 @@ func updateFlashcard(c *gin.Context) {
 		return
 	}
 
-	if err := models.Update(id, userID.(int), input.Word, input.Meaning, input.Example, input.Tags); err != nil {
+	version, ok := h.ifMatchVersion(c, id, userID.(int))
+	if !ok {
+		return
+	}
+
+	card, err := h.Cards.Update(id, userID.(int), version, input.Word, input.Meaning, input.Example, input.Tags)
+	switch {
+	case errors.Is(err, models.ErrCardNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flashcard not found"})
+		return
+	case errors.Is(err, models.ErrVersionConflict):
+		h.preconditionFailed(c, id, userID.(int))
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update flashcard: %v", err)})
 		return
 	}
 
+	c.Header("ETag", cardETag(card))
 	c.JSON(http.StatusOK, gin.H{"message": "Flashcard updated"})
 }
 
-func getFlashcardByID(c *gin.Context) {
+// bulkFlashcards applies a batch of operations in one transaction. If one
+// fails, nothing is saved and the response says which one and why.
+func (h *Handler) bulkFlashcards(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.BulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := models.RunBulk(h.Cards, userID.(int), req)
+	switch {
+	case errors.Is(err, models.ErrBulkFailed):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"committed": false, "error": err.Error(), "results": results})
+		return
+	case errors.Is(err, models.ErrBulkTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to apply operations: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"committed": true, "results": results})
+}
+
+func (h *Handler) getRevisions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	revisions, err := h.Cards.ListRevisions(id, userID.(int))
+	if errors.Is(err, models.ErrCardNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flashcard not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read revisions: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, revisions)
+}
+
+// revertFlashcard sets the card back to a revision and returns the card.
+func (h *Handler) revertFlashcard(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	revisionID, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision"})
+		return
+	}
+
+	card, err := h.Cards.Revert(id, userID.(int), revisionID)
+	switch {
+	case errors.Is(err, models.ErrCardNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flashcard not found"})
+		return
+	case errors.Is(err, models.ErrRevisionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to revert flashcard: %v", err)})
+		return
+	}
+	c.Header("ETag", cardETag(card))
+	c.JSON(http.StatusOK, card)
+}
+
+func (h *Handler) getFlashcardByID(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
@@ -256,18 +560,24 @@ func getFlashcardByID(c *gin.Context) {
 		return
 	}
 
-	card, err := models.GetByID(id, userID.(int))
+	card, err := h.Cards.GetByID(id, userID.(int))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Flashcard not found"})
 		return
 	}
 
+	etag := cardETag(card)
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag, true) {
+		c.Status(http.StatusNotModified)
+		return
+	}
 	c.JSON(http.StatusOK, card)
 }
 
-func getDueFlashcards(c *gin.Context) {
+func (h *Handler) getDueFlashcards(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	cards, err := models.GetDueFlashcards(userID.(int))
+	cards, err := h.Cards.GetDue(userID.(int))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read due flashcards: %v", err)})
 		return
@@ -275,7 +585,7 @@ func getDueFlashcards(c *gin.Context) {
 	c.JSON(http.StatusOK, cards)
 }
 
-func reviewFlashcard(c *gin.Context) {
+func (h *Handler) reviewFlashcard(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -297,7 +607,15 @@ func reviewFlashcard(c *gin.Context) {
 		return
 	}
 
-	if err := models.UpdateAfterReview(id, userID.(int), input.Quality); err != nil {
+	err = h.Cards.Review(id, userID.(int), input.Quality)
+	switch {
+	case errors.Is(err, models.ErrCardNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flashcard not found"})
+		return
+	case errors.Is(err, models.ErrVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "Flashcard was reviewed at the same time, reload it"})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update review: %v", err)})
 		return
 	}
@@ -305,10 +623,10 @@ func reviewFlashcard(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Flashcard review updated"})
 }
 
-func getAllUserTags(c *gin.Context) {
+func (h *Handler) getAllUserTags(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
-	tags, err := models.GetAllTags(userID.(int))
+	tags, err := h.Cards.GetAllTags(userID.(int))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get tags: %v", err)})
 		return
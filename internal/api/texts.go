@@ -0,0 +1,113 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Danyarbrg/flashCards/internal/reading"
+	"github.com/gin-gonic/gin"
+)
+
+const maxTextUpload = 10 << 20
+
+// analyzeText accepts either a JSON body {"text", "lang"} or a multipart
+// form with a .txt/.epub "file" and returns words the user does not know yet.
+func (h *Handler) analyzeText(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxTextUpload)
+
+	var text, lang string
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		lang = c.PostForm("lang")
+		text = c.PostForm("text")
+		if fileHeader, err := c.FormFile("file"); err == nil {
+			f, err := fileHeader.Open()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+				return
+			}
+			data, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+				return
+			}
+			if text, err = reading.ExtractText(fileHeader.Filename, data); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+	} else {
+		var input struct {
+			Text string `json:"text"`
+			Lang string `json:"lang"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+			return
+		}
+		text, lang = input.Text, input.Lang
+	}
+
+	if strings.TrimSpace(text) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Text or file is required"})
+		return
+	}
+	if lang == "" {
+		lang = "en"
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit < 1 {
+		limit = 100
+	}
+
+	known, err := h.Cards.GetUserWords(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read user words: %v", err)})
+		return
+	}
+
+	candidates := reading.Candidates(text, known, reading.Stopwords(lang))
+	total := len(candidates)
+	if total > limit {
+		candidates = candidates[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":      total,
+		"candidates": candidates,
+	})
+}
+
+// createCardsFromText creates cards for the chosen candidates, using the
+// sentence they were found in as the example.
+func (h *Handler) createCardsFromText(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var input struct {
+		Tags  string `json:"tags"`
+		Cards []struct {
+			Word     string `json:"word"`
+			Sentence string `json:"sentence"`
+			Meaning  string `json:"meaning"`
+		} `json:"cards"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+	if len(input.Cards) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one card is required"})
+		return
+	}
+
+	results := make([]cardResult, 0, len(input.Cards))
+	for _, item := range input.Cards {
+		results = append(results, h.createSuggestedCard(c, userID.(int), item.Word, item.Meaning, item.Sentence, input.Tags))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
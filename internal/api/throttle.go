@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/config"
+	"github.com/Danyarbrg/flashCards/internal/throttle"
+	"github.com/gin-gonic/gin"
+)
+
+// Limiters for unauthenticated endpoints. They stay nil, allowing
+// everything, when throttling is disabled.
+var (
+	// ipLimiter counts failed attempts per client IP. It never locks out,
+	// since many users can share an address.
+	ipLimiter *throttle.Limiter
+	// accountLimiter counts failed attempts per account and locks it after
+	// repeated failures.
+	accountLimiter *throttle.Limiter
+	// registerLimiter counts every sign-up per client IP.
+	registerLimiter *throttle.Limiter
+)
+
+func initThrottling(cfg config.AppConfig) {
+	if !cfg.ThrottleEnabled {
+		ipLimiter, accountLimiter, registerLimiter = nil, nil, nil
+		return
+	}
+	policy := throttle.Policy{
+		BaseDelay: cfg.ThrottleBaseDelay,
+		MaxDelay:  cfg.ThrottleMaxDelay,
+		Window:    cfg.ThrottleWindow,
+	}
+
+	ip := policy
+	ip.FreeAttempts = cfg.ThrottleIPAttempts
+	ipLimiter = throttle.New(ip)
+
+	account := policy
+	account.FreeAttempts = cfg.ThrottleAccountAttempts
+	account.LockoutAttempts = cfg.LockoutAttempts
+	account.LockoutDuration = cfg.LockoutDuration
+	accountLimiter = throttle.New(account)
+
+	register := policy
+	register.FreeAttempts = cfg.RegisterIPLimit
+	registerLimiter = throttle.New(register)
+}
+
+// accountKey identifies an account for throttling. kind keeps counters for
+// different actions (password, 2FA code, emails sent) apart.
+func accountKey(kind, id string) string {
+	return kind + ":" + strings.ToLower(strings.TrimSpace(id))
+}
+
+// allowAttempt answers 429 with Retry-After if the client IP or, when
+// given, the account is currently blocked.
+func allowAttempt(c *gin.Context, limiter *throttle.Limiter, key string) bool {
+	wait, ok := ipLimiter.Allow(c.ClientIP())
+	if ok && key != "" {
+		wait, ok = limiter.Allow(key)
+	}
+	if ok {
+		return true
+	}
+
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":       fmt.Sprintf("Too many attempts, try again in %s", (time.Duration(seconds) * time.Second).String()),
+		"retry_after": seconds,
+	})
+	return false
+}
+
+// failAttempt records a failed attempt for the client IP and the account.
+func failAttempt(c *gin.Context, limiter *throttle.Limiter, key string) {
+	ipLimiter.Fail(c.ClientIP())
+	if key != "" {
+		limiter.Fail(key)
+	}
+}
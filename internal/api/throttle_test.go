@@ -0,0 +1,36 @@
+package api_test
+
+import (
+	"crypto/rand"
+	"net/http"
+	"testing"
+
+	"github.com/Danyarbrg/flashCards/internal/api"
+	"github.com/Danyarbrg/flashCards/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestLoginThrottling(t *testing.T) {
+	users := models.NewMemoryUserRepository()
+	router := api.SetupRouter(api.NewHandler(models.NewMemoryCardRepository(), users, models.NewMemoryFrequencyRepository()))
+	email := "user-" + rand.Text() + "@example.com"
+	if _, err := users.Register(email, "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	// THROTTLE_ACCOUNT_ATTEMPTS defaults to 5, after which the account
+	// waits THROTTLE_BASE_DELAY, 1s.
+	wrong := gin.H{"email": email, "password": "wrong"}
+	for i := 0; i < 5; i++ {
+		if w := do(t, router, http.MethodPost, "/login", "", wrong); w.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: status %d: %s", i+1, w.Code, w.Body.String())
+		}
+	}
+	w := do(t, router, http.MethodPost, "/login", "", gin.H{"email": email, "password": "password"})
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("login after 5 failures: status %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q", got)
+	}
+}
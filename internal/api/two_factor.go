@@ -0,0 +1,220 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/auth"
+	"github.com/Danyarbrg/flashCards/internal/config"
+	"github.com/Danyarbrg/flashCards/internal/totp"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	challengeTokenTTL = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+// enrollTwoFactor generates a new TOTP secret. 2FA is only turned on once a
+// code from the authenticator app is confirmed.
+func (h *Handler) enrollTwoFactor(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	user, err := h.Users.GetByID(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read user: %v", err)})
+		return
+	}
+	tf, err := h.Users.GetTwoFactor(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if tf.Enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.Users.SetPendingTwoFactor(user.ID, secret); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": totp.ProvisioningURI(config.InitEnv().TOTPIssuer, user.Email, secret),
+	})
+}
+
+// confirmTwoFactor enables 2FA and returns recovery codes, shown only once.
+func (h *Handler) confirmTwoFactor(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var input struct {
+		Code string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	tf, err := h.Users.GetTwoFactor(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if tf.Enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if tf.Secret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start enrollment first"})
+		return
+	}
+
+	step, ok := totp.Verify(tf.Secret, input.Code, time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.Users.EnableTwoFactor(userID.(int), step, hashes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled, store the recovery codes in a safe place",
+		"recovery_codes": codes,
+	})
+}
+
+func (h *Handler) disableTwoFactor(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var input struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	if _, ok := h.checkPassword(c, userID.(int), input.Password); !ok {
+		return
+	}
+	if ok, err := h.verifySecondFactor(userID.(int), input.Code); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	} else if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	if err := h.Users.DisableTwoFactor(userID.(int)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// loginSecondFactor completes a login started with a challenge token using
+// either a TOTP code or a recovery code.
+func (h *Handler) loginSecondFactor(c *gin.Context) {
+	var input struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
+		return
+	}
+
+	if !allowAttempt(c, nil, "") {
+		return
+	}
+	userID, err := auth.ParseChallengeToken(auth.Keys(), input.ChallengeToken)
+	if err != nil {
+		failAttempt(c, nil, "")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge token"})
+		return
+	}
+
+	key := accountKey("2fa", strconv.Itoa(userID))
+	if !allowAttempt(c, accountLimiter, key) {
+		return
+	}
+	ok, err := h.verifySecondFactor(userID, input.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		failAttempt(c, accountLimiter, key)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+	accountLimiter.Reset(key)
+
+	if user, err := h.Users.GetByID(userID); err != nil || user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		return
+	}
+
+	tokens, err := h.startSession(c, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	tokens["message"] = "Login successful"
+	c.JSON(http.StatusOK, tokens)
+}
+
+// verifySecondFactor accepts a current TOTP code that was not used before,
+// or an unused recovery code.
+func (h *Handler) verifySecondFactor(userID int, code string) (bool, error) {
+	tf, err := h.Users.GetTwoFactor(userID)
+	if err != nil {
+		return false, err
+	}
+	if !tf.Enabled {
+		return false, nil
+	}
+
+	if step, ok := totp.Verify(tf.Secret, code, time.Now()); ok {
+		return h.Users.UseTOTPStep(userID, step)
+	}
+	return h.Users.UseRecoveryCode(userID, auth.HashToken(normalizeRecoveryCode(code)))
+}
+
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 10)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+		}
+		raw := encoding.EncodeToString(buf)
+		codes = append(codes, raw[0:4]+"-"+raw[4:8]+"-"+raw[8:12]+"-"+raw[12:16])
+		hashes = append(hashes, auth.HashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
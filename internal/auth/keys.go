@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// LegacyKeyID identifies the key made from JWT_SECRET. Tokens signed before
+// key IDs existed carry no kid and are verified with it.
+const LegacyKeyID = "default"
+
+// minSecretLength is the shortest HMAC secret accepted from a key file.
+const minSecretLength = 32
+
+// Key is a JWT signing key. Keys loaded from a public key file can only
+// verify tokens.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	sign   interface{}
+	verify interface{}
+}
+
+// Keyring holds the active signing key and every key still accepted for
+// verification.
+type Keyring struct {
+	active *Key
+	keys   map[string]*Key
+	// Issuer is the iss claim of the tokens signed and accepted.
+	Issuer string
+}
+
+var keyring *Keyring
+
+// InitKeyring loads the signing keys once at startup. issuer is the
+// public URL of the server.
+func InitKeyring(dir, activeID, secret, issuer string) error {
+	k, err := LoadKeyring(dir, activeID, secret)
+	if err != nil {
+		return err
+	}
+	k.Issuer = issuer
+	keyring = k
+	return nil
+}
+
+// Keys returns the keyring loaded by InitKeyring.
+func Keys() *Keyring {
+	return keyring
+}
+
+// NewHMACKey makes an HS256 key from a shared secret.
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodHS256, sign: secret, verify: secret}
+}
+
+// NewKeyring builds a keyring signing with the key activeID.
+func NewKeyring(activeID string, keys ...*Key) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]*Key)}
+	for _, key := range keys {
+		if _, ok := k.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		k.keys[key.ID] = key
+	}
+	active, ok := k.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q not found", activeID)
+	}
+	if active.sign == nil {
+		return nil, fmt.Errorf("active key %q has no private key", activeID)
+	}
+	k.active = active
+	return k, nil
+}
+
+// LoadKeyring reads every key file in dir and adds the JWT_SECRET key when
+// secret is set. Files are named <kid>.pem (RSA or Ed25519 private key, or
+// a public key for verification only) or <kid>.key (HS256 secret). The
+// active key defaults to the JWT_SECRET key.
+func LoadKeyring(dir, activeID, secret string) (*Keyring, error) {
+	var keys []*Key
+	if secret != "" {
+		keys = append(keys, NewHMACKey(LegacyKeyID, []byte(secret)))
+	}
+
+	if dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key directory: %w", err)
+		}
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			if entry.IsDir() || (ext != ".pem" && ext != ".key") {
+				continue
+			}
+			key, err := loadKeyFile(filepath.Join(dir, entry.Name()), strings.TrimSuffix(entry.Name(), ext))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	if activeID == "" {
+		if secret == "" {
+			return nil, errors.New("JWT_ACTIVE_KEY_ID is required when JWT_SECRET is not set")
+		}
+		activeID = LegacyKeyID
+	}
+	return NewKeyring(activeID, keys...)
+}
+
+func loadKeyFile(path, id string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Ext(path) == ".key" {
+		secret := []byte(strings.TrimSpace(string(data)))
+		if len(secret) < minSecretLength {
+			return nil, fmt.Errorf("HMAC secret must be at least %d bytes", minSecretLength)
+		}
+		return NewHMACKey(id, secret), nil
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := private.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key")
+		}
+		return newAsymmetricKey(id, private, signer.Public())
+	case "RSA PRIVATE KEY":
+		private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newAsymmetricKey(id, private, private.Public())
+	case "PUBLIC KEY":
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newAsymmetricKey(id, nil, public)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+func newAsymmetricKey(id string, private, public interface{}) (*Key, error) {
+	key := &Key{ID: id, verify: public}
+	if private != nil {
+		key.sign = private
+	}
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	case *ecdsa.PublicKey:
+		return nil, errors.New("ECDSA keys are not supported, use RSA or Ed25519")
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", public)
+	}
+	return key, nil
+}
+
+// Sign signs claims with the active key. typ goes in the header and tells
+// the kinds of token apart.
+func (k *Keyring) Sign(typ string, claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.Method, claims)
+	token.Header["kid"] = k.active.ID
+	token.Header["typ"] = typ
+	return token.SignedString(k.active.sign)
+}
+
+// Parse verifies a token of type typ signed with any key of the keyring
+// and decodes its claims. The token must have been issued by k.Issuer for
+// audience.
+func (k *Keyring) Parse(tokenString, typ, audience string, claims jwt.Claims) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		// RFC 8725: the media type may be given with or without the
+		// "application/" prefix and is case-insensitive.
+		got, _ := token.Header["typ"].(string)
+		if strings.TrimPrefix(strings.ToLower(got), "application/") != typ {
+			return nil, fmt.Errorf("unexpected token type %q", got)
+		}
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			kid = LegacyKeyID
+		}
+		key, ok := k.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		// The algorithm must match the key, so a public key can never be
+		// used as an HMAC secret.
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.verify, nil
+	}, jwt.WithValidMethods([]string{"HS256", "RS256", "EdDSA"}),
+		jwt.WithIssuer(k.Issuer), jwt.WithAudience(audience), jwt.WithExpirationRequired())
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return errors.New("invalid token")
+	}
+	return nil
+}
+
+// JWK is a public key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS returns the public keys of the keyring. HMAC keys are secret and
+// never published.
+func (k *Keyring) JWKS() []JWK {
+	keys := []JWK{}
+	for _, key := range k.keys {
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.verify.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		keys = append(keys, jwk)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Kid < keys[j].Kid })
+	return keys
+}
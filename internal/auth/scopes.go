@@ -0,0 +1,36 @@
+package auth
+
+import "strings"
+
+// Scopes that can be granted to personal access tokens. Login sessions
+// are not restricted by scopes.
+const (
+	ScopeCardsRead  = "cards:read"
+	ScopeCardsWrite = "cards:write"
+	ScopeReview     = "review"
+)
+
+// PersonalTokenPrefix marks personal access tokens so they can be told apart
+// from JWTs in the Authorization header.
+const PersonalTokenPrefix = "fcp_"
+
+var knownScopes = map[string]bool{
+	ScopeCardsRead:  true,
+	ScopeCardsWrite: true,
+	ScopeReview:     true,
+}
+
+// ValidScope reports whether scope can be granted to a token.
+func ValidScope(scope string) bool {
+	return knownScopes[scope]
+}
+
+// HasScope reports whether the comma-separated scope list grants scope.
+func HasScope(scopes, scope string) bool {
+	for _, s := range strings.Split(scopes, ",") {
+		if strings.TrimSpace(s) == scope {
+			return true
+		}
+	}
+	return false
+}
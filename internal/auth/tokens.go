@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Token types (typ header) and audiences. Each kind of token has its own,
+// so a token made for one purpose is never accepted for another.
+const (
+	// AccessTokenType is the RFC 9068 type of access tokens.
+	AccessTokenType = "at+jwt"
+	// Audience is the aud claim of access tokens. Other services that
+	// verify them with the JWKS should check it along with iss and typ.
+	Audience = "flashcards-api"
+
+	challengeType      = "2fa+jwt"
+	challengeAudience  = "flashcards-2fa"
+	loginStateType     = "oidc-state+jwt"
+	loginStateAudience = "flashcards-oidc-state"
+)
+
+// registeredClaims are the standard claims of a token for audience.
+func registeredClaims(keys *Keyring, audience string, ttl time.Duration) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Issuer:    keys.Issuer,
+		Audience:  jwt.ClaimStrings{audience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+}
+
+// Claims are the claims carried by access tokens.
+type Claims struct {
+	UserID    int `json:"user_id"`
+	SessionID int `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// NewAccessToken signs a short-lived access token bound to a session.
+func NewAccessToken(keys *Keyring, userID, sessionID int, ttl time.Duration) (string, error) {
+	claims := Claims{
+		UserID:           userID,
+		SessionID:        sessionID,
+		RegisteredClaims: registeredClaims(keys, Audience, ttl),
+	}
+	claims.Subject = strconv.Itoa(userID)
+	return keys.Sign(AccessTokenType, claims)
+}
+
+// ParseAccessToken validates the signature, type, issuer, audience and
+// expiry of an access token.
+func ParseAccessToken(keys *Keyring, tokenString string) (Claims, error) {
+	var claims Claims
+	if err := keys.Parse(tokenString, AccessTokenType, Audience, &claims); err != nil {
+		return claims, fmt.Errorf("invalid token: %w", err)
+	}
+	if claims.UserID == 0 || claims.SessionID == 0 {
+		return claims, errors.New("token is not bound to a session")
+	}
+	return claims, nil
+}
+
+// NewOpaqueToken returns a random URL-safe token and its hash for storage.
+func NewOpaqueToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+// HashToken hashes opaque tokens before they are stored or looked up.
+// The tokens are random, so a plain SHA-256 is sufficient.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// challengeClaims identify a user who passed the password check but still
+// has to provide a second factor.
+type challengeClaims struct {
+	UserID  int    `json:"user_id"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+const challengePurpose = "2fa"
+
+// NewChallengeToken signs a short-lived token for the second login step.
+// Its type and audience differ from access tokens', so it is never accepted
+// as one.
+func NewChallengeToken(keys *Keyring, userID int, ttl time.Duration) (string, error) {
+	claims := challengeClaims{
+		UserID:           userID,
+		Purpose:          challengePurpose,
+		RegisteredClaims: registeredClaims(keys, challengeAudience, ttl),
+	}
+	return keys.Sign(challengeType, claims)
+}
+
+// ParseChallengeToken returns the user ID of a valid challenge token.
+func ParseChallengeToken(keys *Keyring, tokenString string) (int, error) {
+	var claims challengeClaims
+	if err := keys.Parse(tokenString, challengeType, challengeAudience, &claims); err != nil {
+		return 0, fmt.Errorf("invalid challenge token: %w", err)
+	}
+	if claims.Purpose != challengePurpose || claims.UserID == 0 {
+		return 0, errors.New("not a challenge token")
+	}
+	return claims.UserID, nil
+}
+
+// LoginState is the OpenID Connect login state kept in a cookie between
+// the redirect to the provider and the callback.
+type LoginState struct {
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	Purpose      string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+const loginStatePurpose = "oidc"
+
+// NewLoginStateToken signs the login state so it cannot be tampered with.
+func NewLoginStateToken(keys *Keyring, state LoginState, ttl time.Duration) (string, error) {
+	state.Purpose = loginStatePurpose
+	state.RegisteredClaims = registeredClaims(keys, loginStateAudience, ttl)
+	return keys.Sign(loginStateType, state)
+}
+
+// ParseLoginStateToken verifies a token made by NewLoginStateToken.
+func ParseLoginStateToken(keys *Keyring, tokenString string) (LoginState, error) {
+	var state LoginState
+	if err := keys.Parse(tokenString, loginStateType, loginStateAudience, &state); err != nil {
+		return state, fmt.Errorf("invalid login state: %w", err)
+	}
+	if state.Purpose != loginStatePurpose || state.State == "" {
+		return state, errors.New("not a login state token")
+	}
+	return state, nil
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestKeyring(t *testing.T, issuer string) *Keyring {
+	t.Helper()
+	k, err := NewKeyring(LegacyKeyID, NewHMACKey(LegacyKeyID, []byte("test-secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.Issuer = issuer
+	return k
+}
+
+func TestAccessToken(t *testing.T) {
+	keys := newTestKeyring(t, "https://app.test")
+	token, err := NewAccessToken(keys, 7, 3, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ParseAccessToken(keys, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != 7 || claims.SessionID != 3 || claims.Issuer != "https://app.test" {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	other := newTestKeyring(t, "https://other.test")
+	if _, err := ParseAccessToken(other, token); err == nil {
+		t.Error("token from another issuer was accepted")
+	}
+}
+
+// Tokens signed with the same key for another purpose are not access
+// tokens, and the other way round.
+func TestTokenKindsAreSeparate(t *testing.T) {
+	keys := newTestKeyring(t, "https://app.test")
+	access, err := NewAccessToken(keys, 7, 3, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, err := NewChallengeToken(keys, 7, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := NewLoginStateToken(keys, LoginState{State: "s", Nonce: "n"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseAccessToken(keys, challenge); err == nil {
+		t.Error("challenge token accepted as an access token")
+	}
+	if _, err := ParseAccessToken(keys, state); err == nil {
+		t.Error("login state accepted as an access token")
+	}
+	if _, err := ParseChallengeToken(keys, access); err == nil {
+		t.Error("access token accepted as a challenge token")
+	}
+	if _, err := ParseChallengeToken(keys, state); err == nil {
+		t.Error("login state accepted as a challenge token")
+	}
+	if _, err := ParseLoginStateToken(keys, challenge); err == nil {
+		t.Error("challenge token accepted as a login state")
+	}
+	if _, err := ParseChallengeToken(keys, challenge); err != nil {
+		t.Errorf("challenge token: %v", err)
+	}
+	if _, err := ParseLoginStateToken(keys, state); err != nil {
+		t.Errorf("login state: %v", err)
+	}
+}
+
+func TestParseAccessTokenChecksClaims(t *testing.T) {
+	keys := newTestKeyring(t, "https://app.test")
+	valid := func() Claims {
+		return Claims{UserID: 7, SessionID: 3, RegisteredClaims: registeredClaims(keys, Audience, time.Minute)}
+	}
+
+	tests := []struct {
+		name   string
+		typ    string
+		claims func() Claims
+	}{
+		{"no type", "", valid},
+		{"JWT type", "JWT", valid},
+		{"no audience", AccessTokenType, func() Claims { c := valid(); c.Audience = nil; return c }},
+		{"other audience", AccessTokenType, func() Claims { c := valid(); c.Audience = jwt.ClaimStrings{"other"}; return c }},
+		{"no issuer", AccessTokenType, func() Claims { c := valid(); c.Issuer = ""; return c }},
+		{"no expiry", AccessTokenType, func() Claims { c := valid(); c.ExpiresAt = nil; return c }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := keys.Sign(tt.typ, tt.claims())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ParseAccessToken(keys, token); err == nil {
+				t.Error("token was accepted")
+			}
+		})
+	}
+
+	token, err := keys.Sign("application/AT+JWT", valid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseAccessToken(keys, token); err != nil {
+		t.Errorf("media type with prefix: %v", err)
+	}
+}
@@ -3,17 +3,89 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type AppConfig struct {
-	Port		string
-	DBPath		string
-	JWTSecret	string
+	Port            string
+	DBPath          string
+	JWTSecret       string
+	JWTKeysDir      string
+	JWTActiveKeyID  string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	DictionaryPaths []string
+
+	SQLiteJournalMode string
+	SQLiteSynchronous string
+	SQLiteBusyTimeout time.Duration
+	SQLiteForeignKeys bool
+	SQLiteReadConns   int
+
+	BackupDir      string
+	BackupInterval time.Duration
+	BackupKeep     int
+	TrashRetention time.Duration
+
+	Translator       string
+	TranslatorURL    string
+	TranslatorAPIKey string
+	TranslateSource  string
+	TranslateTarget  string
+
+	FrequencyListsPath string
+	Lemmatizer         string
+
+	AppBaseURL               string
+	Mailer                   string
+	MailFrom                 string
+	MailLogPath              string
+	SMTPHost                 string
+	SMTPPort                 string
+	SMTPUsername             string
+	SMTPPassword             string
+	RequireEmailVerification bool
+	TOTPIssuer               string
+
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	OIDCScopes       []string
+
+	ThrottleEnabled         bool
+	ThrottleAccountAttempts int
+	ThrottleIPAttempts      int
+	ThrottleBaseDelay       time.Duration
+	ThrottleMaxDelay        time.Duration
+	LockoutAttempts         int
+	LockoutDuration         time.Duration
+	ThrottleWindow          time.Duration
+	RegisterIPLimit         int
+
+	AdminEmails []string
 }
 
+var (
+	loadOnce sync.Once
+	loaded   AppConfig
+)
+
+// InitEnv returns the configuration. The environment is only read on the
+// first call; later calls return the same values.
 func InitEnv() AppConfig {
+	loadOnce.Do(func() {
+		loaded = load()
+	})
+	return loaded
+}
+
+func load() AppConfig {
 	_ = godotenv.Load()
 
 	port := os.Getenv("PORT")
@@ -26,14 +98,134 @@ func InitEnv() AppConfig {
 		dbURL = "flashcards.db"
 	}
 
+	// JWT_SECRET may be omitted once all signing keys live in JWT_KEYS_DIR.
 	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
+	jwtKeysDir := os.Getenv("JWT_KEYS_DIR")
+	if jwtSecret == "" && jwtKeysDir == "" {
 		log.Fatal("JWT_SECRET is required.")
 	}
 
+	// Comma-separated list of StarDict/dictd files or directories.
+	var dictPaths []string
+	if paths := os.Getenv("DICTIONARY_PATHS"); paths != "" {
+		dictPaths = strings.Split(paths, ",")
+	}
+
+	baseURL := os.Getenv("APP_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:" + port
+	}
+
+	totpIssuer := os.Getenv("TOTP_ISSUER")
+	if totpIssuer == "" {
+		totpIssuer = "FlashCards"
+	}
+
+	oidcRedirectURL := os.Getenv("OIDC_REDIRECT_URL")
+	if oidcRedirectURL == "" {
+		oidcRedirectURL = strings.TrimRight(baseURL, "/") + "/auth/oidc/callback"
+	}
+	var oidcScopes []string
+	if scopes := os.Getenv("OIDC_SCOPES"); scopes != "" {
+		oidcScopes = strings.Fields(strings.ReplaceAll(scopes, ",", " "))
+	}
+
+	// Comma-separated emails that are always given the admin role.
+	var adminEmails []string
+	if emails := os.Getenv("ADMIN_EMAILS"); emails != "" {
+		adminEmails = strings.Split(emails, ",")
+	}
+
 	return AppConfig{
-		Port:		port,
-		DBPath: 	dbURL,
-		JWTSecret: jwtSecret,
+		Port:            port,
+		DBPath:          dbURL,
+		JWTSecret:       jwtSecret,
+		JWTKeysDir:      jwtKeysDir,
+		JWTActiveKeyID:  os.Getenv("JWT_ACTIVE_KEY_ID"),
+		AccessTokenTTL:  durationEnv("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: durationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		DictionaryPaths: dictPaths,
+
+		SQLiteJournalMode: stringEnv("SQLITE_JOURNAL_MODE", "WAL"),
+		SQLiteSynchronous: stringEnv("SQLITE_SYNCHRONOUS", "NORMAL"),
+		SQLiteBusyTimeout: durationEnv("SQLITE_BUSY_TIMEOUT", 5*time.Second),
+		SQLiteForeignKeys: os.Getenv("SQLITE_FOREIGN_KEYS") != "false",
+		SQLiteReadConns:   intEnv("SQLITE_READ_CONNECTIONS", 4),
+
+		BackupDir:      stringEnv("BACKUP_DIR", "backups"),
+		BackupInterval: durationEnv("BACKUP_INTERVAL", 0),
+		BackupKeep:     intEnv("BACKUP_KEEP", 7),
+		TrashRetention: durationEnv("TRASH_RETENTION", 30*24*time.Hour),
+
+		Translator:       os.Getenv("TRANSLATOR"),
+		TranslatorURL:    os.Getenv("TRANSLATOR_URL"),
+		TranslatorAPIKey: os.Getenv("TRANSLATOR_API_KEY"),
+		TranslateSource:  os.Getenv("TRANSLATE_SOURCE"),
+		TranslateTarget:  os.Getenv("TRANSLATE_TARGET"),
+
+		FrequencyListsPath: os.Getenv("FREQUENCY_LISTS_PATH"),
+		Lemmatizer:         os.Getenv("LEMMATIZER"),
+
+		AppBaseURL:               strings.TrimRight(baseURL, "/"),
+		Mailer:                   os.Getenv("MAILER"),
+		MailFrom:                 os.Getenv("MAIL_FROM"),
+		MailLogPath:              os.Getenv("MAIL_LOG_PATH"),
+		SMTPHost:                 os.Getenv("SMTP_HOST"),
+		SMTPPort:                 os.Getenv("SMTP_PORT"),
+		SMTPUsername:             os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:             os.Getenv("SMTP_PASSWORD"),
+		RequireEmailVerification: os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true",
+		TOTPIssuer:               totpIssuer,
+
+		OIDCIssuer:       os.Getenv("OIDC_ISSUER"),
+		OIDCClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		OIDCClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:  oidcRedirectURL,
+		OIDCScopes:       oidcScopes,
+
+		ThrottleEnabled:         os.Getenv("THROTTLE_ENABLED") != "false",
+		ThrottleAccountAttempts: intEnv("THROTTLE_ACCOUNT_ATTEMPTS", 5),
+		ThrottleIPAttempts:      intEnv("THROTTLE_IP_ATTEMPTS", 20),
+		ThrottleBaseDelay:       durationEnv("THROTTLE_BASE_DELAY", time.Second),
+		ThrottleMaxDelay:        durationEnv("THROTTLE_MAX_DELAY", 5*time.Minute),
+		LockoutAttempts:         intEnv("LOCKOUT_ATTEMPTS", 10),
+		LockoutDuration:         durationEnv("LOCKOUT_DURATION", 15*time.Minute),
+		ThrottleWindow:          durationEnv("THROTTLE_WINDOW", time.Hour),
+		RegisterIPLimit:         intEnv("REGISTER_IP_LIMIT", 10),
+
+		AdminEmails: adminEmails,
+	}
+}
+
+func stringEnv(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// durationEnv parses a Go duration ("15m", "720h") from the environment.
+func durationEnv(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Fatalf("%s must be a positive duration, got %q.", key, value)
+	}
+	return d
+}
+
+// intEnv parses a non-negative integer from the environment.
+func intEnv(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Fatalf("%s must be a non-negative integer, got %q.", key, value)
 	}
+	return n
 }
@@ -0,0 +1,284 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrBackupUnsupported is returned for PostgreSQL, which is backed up with
+// its own tools such as pg_dump.
+var ErrBackupUnsupported = errors.New("backups are only supported for SQLite; use pg_dump for PostgreSQL")
+
+// ErrDatabaseInUse is returned by Restore while another process, such as
+// the server, has the database open.
+var ErrDatabaseInUse = errors.New("database is in use; stop the server before restoring")
+
+const (
+	backupPrefix     = "flashcards-"
+	backupSuffix     = ".db"
+	backupTimeFormat = "20060102-150405.000"
+)
+
+// BackupFile is a backup in the backup directory.
+type BackupFile struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var backupMu sync.Mutex
+
+// Backup writes a consistent snapshot of the open SQLite database to dest
+// with VACUUM INTO. It runs while the server is serving requests, blocking
+// neither readers nor writers. dest must not exist.
+func Backup(dest string) error {
+	if Dialect != SQLite {
+		return ErrBackupUnsupported
+	}
+	backupMu.Lock()
+	defer backupMu.Unlock()
+
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("backup %s already exists", dest)
+	}
+	// The snapshot is written next to dest and renamed, so a failed backup
+	// never leaves a truncated file that looks like a good one.
+	tmp := dest + ".tmp"
+	os.Remove(tmp)
+	// The copy runs on a connection of its own from the read pool, which
+	// under WAL reads a snapshot while the writer goes on committing.
+	ctx := context.Background()
+	conn, err := Read.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	defer conn.Close()
+	if Read != DB {
+		// Read connections are query-only, which refuses VACUUM INTO too.
+		// This one may write for the copy and is then discarded, so it
+		// never goes back to the pool writable.
+		defer conn.Raw(func(any) error { return driver.ErrBadConn })
+		if _, err := conn.ExecContext(ctx, `PRAGMA query_only = false`); err != nil {
+			return fmt.Errorf("failed to back up database: %w", err)
+		}
+	}
+	if _, err := conn.ExecContext(ctx, `VACUUM INTO ?`, tmp); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return os.Rename(tmp, dest)
+}
+
+// BackupToDir writes a timestamped backup into dir and then deletes all but
+// the newest keep backups there. keep 0 keeps every backup.
+func BackupToDir(dir string, keep int) (BackupFile, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return BackupFile{}, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	now := time.Now().UTC()
+	name := backupPrefix + now.Format(backupTimeFormat) + backupSuffix
+	if err := Backup(filepath.Join(dir, name)); err != nil {
+		return BackupFile{}, err
+	}
+	if err := pruneBackups(dir, keep); err != nil {
+		return BackupFile{}, err
+	}
+
+	info, err := os.Stat(filepath.Join(dir, name))
+	if err != nil {
+		return BackupFile{}, err
+	}
+	return BackupFile{Name: name, Size: info.Size(), CreatedAt: now.Truncate(time.Millisecond)}, nil
+}
+
+// ListBackups returns the backups in dir, newest first.
+func ListBackups(dir string) ([]BackupFile, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []BackupFile
+	for _, entry := range entries {
+		createdAt, ok := parseBackupName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, BackupFile{Name: entry.Name(), Size: info.Size(), CreatedAt: createdAt})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Name > backups[j].Name })
+	return backups, nil
+}
+
+// IsBackupName reports whether name is a file name BackupToDir would use,
+// which makes it safe to join to the backup directory.
+func IsBackupName(name string) bool {
+	_, ok := parseBackupName(name)
+	return ok
+}
+
+func parseBackupName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+		return time.Time{}, false
+	}
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix)
+	t, err := time.Parse(backupTimeFormat, stamp)
+	return t, err == nil
+}
+
+func pruneBackups(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	backups, err := ListBackups(dir)
+	if err != nil {
+		return err
+	}
+	for _, b := range backups[min(keep, len(backups)):] {
+		if err := os.Remove(filepath.Join(dir, b.Name)); err != nil {
+			return fmt.Errorf("failed to delete old backup: %w", err)
+		}
+	}
+	return nil
+}
+
+// CheckBackup opens the SQLite file at path read-only and verifies that it
+// passes an integrity check and has a schema this build knows.
+func CheckBackup(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	conn, err := sql.Open("sqlite3", sqliteURI(path, url.Values{"mode": {"ro"}}))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var result string
+	if err := conn.QueryRow(`PRAGMA integrity_check`).Scan(&result); err != nil {
+		return fmt.Errorf("failed to check %s: %w", path, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%s failed the integrity check: %s", path, result)
+	}
+
+	var version int
+	if err := conn.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("%s is not a flashcards database: %w", path, err)
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	if latest := migrations[len(migrations)-1].Version; version > latest {
+		return fmt.Errorf("%s has schema version %d, newer than this build (%d)", path, version, latest)
+	}
+	return nil
+}
+
+// Restore replaces the SQLite database at dbPath with the backup at src
+// after checking it. The server must be stopped; Restore fails with
+// ErrDatabaseInUse if anything has the database open. The replaced database
+// and its WAL are kept as dbPath.before-restore. Older backups are migrated
+// on the next start.
+func Restore(src, dbPath string) error {
+	if _, _, dialect, err := driverFor(dbPath); err != nil || dialect != SQLite {
+		return ErrBackupUnsupported
+	}
+	if err := CheckBackup(src); err != nil {
+		return err
+	}
+	if err := checkNotInUse(dbPath); err != nil {
+		return err
+	}
+
+	// Copy first so that a failure leaves the current database in place.
+	tmp := dbPath + ".restoring"
+	if err := copyFile(src, tmp); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to copy backup: %w", err)
+	}
+
+	previous := dbPath + ".before-restore"
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		os.Remove(previous + suffix)
+		if err := os.Rename(dbPath+suffix, previous+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			os.Remove(tmp)
+			return fmt.Errorf("failed to move current database aside: %w", err)
+		}
+	}
+	return os.Rename(tmp, dbPath)
+}
+
+// checkNotInUse fails with ErrDatabaseInUse if another connection has the
+// SQLite database at path open. In WAL mode BEGIN EXCLUSIVE does not wait
+// for idle connections, so the check uses exclusive locking mode, which
+// fails unless every other connection is closed.
+func checkNotInUse(path string) error {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	params := url.Values{
+		"mode":          {"rw"},
+		"_locking_mode": {"EXCLUSIVE"},
+		"_txlock":       {"exclusive"},
+		"_busy_timeout": {"0"},
+	}
+	conn, err := sql.Open("sqlite3", sqliteURI(path, params))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	tx, err := conn.Begin()
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrBusy {
+		return ErrDatabaseInUse
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return tx.Rollback()
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,117 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/db"
+)
+
+// Backup file names may contain characters that mean something in a URI.
+func TestCheckBackupEscapesPath(t *testing.T) {
+	dir := t.TempDir()
+	open(t, filepath.Join(dir, "test.db"))
+	if _, err := db.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+
+	backup := filepath.Join(dir, "backup?mode=rwc#1 %41.db")
+	if err := db.Backup(backup); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CheckBackup(backup); err != nil {
+		t.Errorf("CheckBackup: %v", err)
+	}
+	// Opening the wrong path would create it rather than fail.
+	if _, err := os.Stat(filepath.Join(dir, "backup")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("CheckBackup opened another file: %v", err)
+	}
+	if err := db.CheckBackup(filepath.Join(dir, "missing?.db")); err == nil {
+		t.Error("CheckBackup of a missing file succeeded")
+	}
+}
+
+func TestRestoreRefusesOpenDatabase(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	open(t, dbPath)
+	if _, err := db.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	backup := filepath.Join(dir, "backup.db")
+	if err := db.Backup(backup); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Restore(backup, dbPath); !errors.Is(err, db.ErrDatabaseInUse) {
+		t.Fatalf("restore while the database is open: err = %v", err)
+	}
+	if _, err := os.Stat(dbPath + ".before-restore"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("a refused restore moved the database aside")
+	}
+
+	db.Close()
+	if err := db.Restore(backup, dbPath); err != nil {
+		t.Fatalf("restore after closing the database: %v", err)
+	}
+	if _, err := os.Stat(dbPath + ".before-restore"); err != nil {
+		t.Errorf("previous database was not kept: %v", err)
+	}
+}
+
+// A backup reads a snapshot on a connection of its own, so it neither
+// waits for the writer nor sees its uncommitted changes.
+func TestBackupDoesNotBlockWriters(t *testing.T) {
+	dir := t.TempDir()
+	opts := db.SQLiteOptions{JournalMode: "WAL", BusyTimeout: 5 * time.Second, ForeignKeys: true, ReadConns: 1}
+	if err := db.Open(filepath.Join(dir, "test.db"), opts); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`INSERT INTO users (email, password_hash) VALUES (?, ?)`, "user@example.com", "hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	backup := filepath.Join(dir, "backup.db")
+	done := make(chan error, 1)
+	go func() { done <- db.Backup(backup) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("backup waited for the write transaction")
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := sql.Open("sqlite3", backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var n int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&n); err != nil || n != 0 {
+		t.Errorf("backup has %d users (err %v), want the snapshot before the insert", n, err)
+	}
+
+	// The read pool has a single connection, which must still refuse writes.
+	if _, err := db.Read.Exec(`DELETE FROM users`); err == nil {
+		t.Error("the read pool can write after a backup")
+	}
+}
@@ -3,68 +3,86 @@ package db
 import (
 	"database/sql"
 	"log"
+)
 
-	_ "github.com/mattn/go-sqlite3"
+var (
+	// DB is used for writes and transactions. For SQLite it is a single
+	// connection, so writers queue in the pool instead of competing for
+	// the file lock.
+	DB *sql.DB
+	// Read is used for plain queries. For SQLite it is a separate pool of
+	// read-only connections; for PostgreSQL it is the same pool as DB.
+	Read *sql.DB
 )
 
-var DB *sql.DB
+// Open connects to the database without touching the schema. dbPath is a
+// SQLite file or a postgres:// URL; opts only apply to SQLite.
+func Open(dbPath string, opts SQLiteOptions) error {
+	driverName, dsn, dialect, err := driverFor(dbPath)
+	if err != nil {
+		log.Printf("DB connection error: %v", err)
+		return err
+	}
 
-func InitDB(dbPath string) error {
-	var err error
+	if dialect == Postgres {
+		if DB, err = connect(driverName, dsn); err != nil {
+			return err
+		}
+		Read = DB
+		Dialect = dialect
+		return nil
+	}
 
-	if DB, err = sql.Open("sqlite3", dbPath); err != nil {
-		log.Fatalf("DB connection error: %v", err)
+	if DB, err = connect(driverName, sqliteDSN(dsn, opts, false)); err != nil {
 		return err
 	}
+	DB.SetMaxOpenConns(1)
+	Read = DB
+	if !inMemory(dsn) {
+		if Read, err = connect(driverName, sqliteDSN(dsn, opts, true)); err != nil {
+			DB.Close()
+			return err
+		}
+		Read.SetMaxOpenConns(opts.ReadConns)
+	}
+	Dialect = dialect
+	return nil
+}
 
-	if err = DB.Ping(); err != nil {
-		log.Fatalf("DB ping error: %v", err)
-		return err
+func connect(driverName, dsn string) (*sql.DB, error) {
+	conn, err := sql.Open(driverName, dsn)
+	if err != nil {
+		log.Printf("DB connection error: %v", err)
+		return nil, err
+	}
+	if err = conn.Ping(); err != nil {
+		log.Printf("DB ping error: %v", err)
+		conn.Close()
+		return nil, err
 	}
+	return conn, nil
+}
 
-	// Creating users table
-	createUsersTable := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		email TEXT NOT NULL UNIQUE,
-		password_hash TEXT NOT NULL
-	);`
-	if _, err = DB.Exec(createUsersTable); err != nil {
-		log.Fatalf("Creating users table error: %v", err)
-		return err
+// Close closes both pools.
+func Close() error {
+	if Read != nil && Read != DB {
+		Read.Close()
 	}
+	return DB.Close()
+}
 
-	// Creating flashcards table with new columns
-	createFlashcardsTable := `
-	CREATE TABLE IF NOT EXISTS flashcards (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
-		word TEXT NOT NULL,
-		meaning TEXT NOT NULL,
-		example TEXT,
-		tags TEXT DEFAULT '',
-		next_review DATETIME DEFAULT CURRENT_TIMESTAMP,
-		interval INTEGER DEFAULT 1,
-		repetitions INTEGER DEFAULT 0,
-		ef REAL DEFAULT 2.5,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP, -- Новое поле для даты создания
-		FOREIGN KEY (user_id) REFERENCES users(id)
-	);`
-	if _, err = DB.Exec(createFlashcardsTable); err != nil {
-		log.Fatalf("Creating flashcards table error: %v", err)
+// InitDB connects to the database and applies pending migrations.
+func InitDB(dbPath string, opts SQLiteOptions) error {
+	if err := Open(dbPath, opts); err != nil {
+		log.Fatalf("DB connection error: %v", err)
 		return err
 	}
-	
-	// Creating indexes.
-	createIndexes := `
-	CREATE INDEX IF NOT EXISTS idx_user_id ON flashcards(user_id);
-	CREATE INDEX IF NOT EXISTS idx_next_review ON flashcards(next_review);
-	`
-	if _, err = DB.Exec(createIndexes); err != nil {
-		log.Fatalf("Creating indexes error: %v", err)
+
+	if _, err := MigrateUp(); err != nil {
+		log.Fatalf("DB migration error: %v", err)
 		return err
 	}
 
 	log.Println("DB connected and ready.")
 	return nil
-}
\ No newline at end of file
+}
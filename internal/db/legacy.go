@@ -0,0 +1,124 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// baselineLegacySchema upgrades a database created by InitDB before
+// versioned migrations existed. Such databases have tables but no
+// schema_migrations, and may predate any column added since. The missing
+// columns are added, then the initial migration is recorded as applied.
+func baselineLegacySchema() error {
+	// PostgreSQL support came with migrations, so there is nothing to
+	// upgrade there.
+	if Dialect != SQLite {
+		return nil
+	}
+	tracked, err := tableExists(DB, "schema_migrations")
+	if err != nil || tracked {
+		return err
+	}
+	legacy, err := tableExists(DB, "users")
+	if err != nil || !legacy {
+		return err
+	}
+	log.Println("Upgrading database created before schema migrations.")
+
+	// Users registered before email verification existed count as verified.
+	added, err := addColumnIfMissing("users", "email_verified_at", "DATETIME")
+	if err != nil {
+		return err
+	}
+	if added {
+		if _, err = DB.Exec(`UPDATE users SET email_verified_at = ?`, Time(time.Now())); err != nil {
+			return fmt.Errorf("failed to mark existing users as verified: %w", err)
+		}
+	}
+	if err := addColumns("users", [][2]string{
+		{"totp_secret", "TEXT"},
+		{"totp_enabled_at", "DATETIME"},
+		{"totp_last_step", "INTEGER DEFAULT 0"},
+		{"role", "TEXT NOT NULL DEFAULT 'user'"},
+		{"disabled_at", "DATETIME"},
+	}); err != nil {
+		return err
+	}
+
+	// Early flashcards tables lack the scheduling columns and created_at.
+	// SQLite cannot add a column defaulting to CURRENT_TIMESTAMP, so the
+	// dates of existing rows are filled in afterwards.
+	hasCards, err := tableExists(DB, "flashcards")
+	if err != nil {
+		return err
+	}
+	if hasCards {
+		if err := addColumns("flashcards", [][2]string{
+			{"tags", "TEXT DEFAULT ''"},
+			{"next_review", "DATETIME"},
+			{"interval", "INTEGER DEFAULT 1"},
+			{"repetitions", "INTEGER DEFAULT 0"},
+			{"ef", "REAL DEFAULT 2.5"},
+			{"created_at", "DATETIME"},
+		}); err != nil {
+			return err
+		}
+		for _, column := range []string{"next_review", "created_at"} {
+			query := fmt.Sprintf(`UPDATE flashcards SET %s = ? WHERE %s IS NULL`, column, column)
+			if _, err := DB.Exec(query, Time(time.Now())); err != nil {
+				return fmt.Errorf("failed to fill flashcards.%s: %w", column, err)
+			}
+		}
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	if _, err := DB.Exec(createMigrationsTable()); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	// The initial migration only uses CREATE ... IF NOT EXISTS, so running
+	// it adds whatever tables the old database is missing.
+	return runMigration(migrations[0], migrations[0].Up, true)
+}
+
+func addColumns(table string, columns [][2]string) error {
+	for _, c := range columns {
+		if _, err := addColumnIfMissing(table, c[0], c[1]); err != nil {
+			return fmt.Errorf("failed to add %s.%s: %w", table, c[0], err)
+		}
+	}
+	return nil
+}
+
+// addColumnIfMissing adds a column to an existing table, since
+// CREATE TABLE IF NOT EXISTS does not touch tables that already exist.
+func addColumnIfMissing(table, column, definition string) (bool, error) {
+	rows, err := DB.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return false, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	rows.Close()
+
+	_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err == nil, err
+}
@@ -0,0 +1,86 @@
+package db_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/db"
+	"github.com/Danyarbrg/flashCards/internal/db/dbtest"
+)
+
+func TestSQLiteMigrations(t *testing.T) {
+	open(t, filepath.Join(t.TempDir(), "test.db"))
+	testMigrations(t)
+}
+
+func TestPostgresMigrations(t *testing.T) {
+	open(t, dbtest.PostgresURL(t))
+	testMigrations(t)
+}
+
+func open(t *testing.T, dbPath string) {
+	t.Helper()
+	opts := db.SQLiteOptions{JournalMode: "WAL", BusyTimeout: 5 * time.Second, ForeignKeys: true}
+	if err := db.Open(dbPath, opts); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+}
+
+// testMigrations applies every migration to an empty database, reverts
+// them all with data in the tables and applies them again.
+func testMigrations(t *testing.T) {
+	status, err := db.MigrationsStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := len(status)
+
+	if n, err := db.MigrateUp(); err != nil || n != total {
+		t.Fatalf("MigrateUp applied %d of %d: %v", n, total, err)
+	}
+	if n, err := db.MigrateUp(); err != nil || n != 0 {
+		t.Fatalf("second MigrateUp applied %d: %v", n, err)
+	}
+	checkApplied(t, total)
+
+	var userID int
+	err = db.DB.QueryRow(`INSERT INTO users (email, password_hash) VALUES (?, ?) RETURNING id`, "user@example.com", "hash").Scan(&userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.DB.Exec(`INSERT INTO flashcards (user_id, word, meaning, example, tags, next_review, interval, repetitions, ef, created_at)
+		VALUES (?, 'word', 'meaning', '', 'tag', ?, 1, 0, 2.5, ?)`, userID, db.Time(time.Now()), db.Time(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := db.MigrateDown(total); err != nil || n != total {
+		t.Fatalf("MigrateDown reverted %d of %d: %v", n, total, err)
+	}
+	checkApplied(t, 0)
+	if _, err := db.DB.Exec(`SELECT COUNT(*) FROM flashcards`); err == nil {
+		t.Error("flashcards still exists after reverting every migration")
+	}
+
+	if n, err := db.MigrateUp(); err != nil || n != total {
+		t.Fatalf("MigrateUp after MigrateDown applied %d of %d: %v", n, total, err)
+	}
+	checkApplied(t, total)
+}
+
+// checkApplied checks that the first n migrations, and no others, are
+// applied.
+func checkApplied(t *testing.T, n int) {
+	t.Helper()
+	status, err := db.MigrationsStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range status {
+		if applied := s.AppliedAt != nil; applied != (i < n) {
+			t.Errorf("migration %04d_%s: applied %v", s.Version, s.Name, applied)
+		}
+	}
+}
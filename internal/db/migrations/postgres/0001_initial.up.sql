@@ -0,0 +1,113 @@
+-- PostgreSQL version of the initial schema. Times are stored as
+-- TIMESTAMPTZ and connections use UTC.
+
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	email TEXT NOT NULL UNIQUE,
+	password_hash TEXT NOT NULL,
+	email_verified_at TIMESTAMPTZ,
+	totp_secret TEXT,
+	totp_enabled_at TIMESTAMPTZ,
+	totp_last_step BIGINT DEFAULT 0,
+	role TEXT NOT NULL DEFAULT 'user',
+	disabled_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	code_hash TEXT NOT NULL,
+	used_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	issuer TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	email TEXT DEFAULT '',
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (issuer, subject)
+);
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	purpose TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	data TEXT DEFAULT '',
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS flashcards (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	word TEXT NOT NULL,
+	meaning TEXT NOT NULL,
+	example TEXT,
+	tags TEXT DEFAULT '',
+	next_review TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	interval INTEGER DEFAULT 1,
+	repetitions INTEGER DEFAULT 0,
+	ef DOUBLE PRECISION DEFAULT 2.5,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS translations (
+	id SERIAL PRIMARY KEY,
+	provider TEXT NOT NULL,
+	source_lang TEXT NOT NULL,
+	target_lang TEXT NOT NULL,
+	text TEXT NOT NULL,
+	result TEXT NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (provider, source_lang, target_lang, text)
+);
+
+CREATE TABLE IF NOT EXISTS frequency_lists (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	language TEXT DEFAULT '',
+	word_count INTEGER DEFAULT 0,
+	source_hash TEXT DEFAULT '',
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS frequency_words (
+	list_id INTEGER NOT NULL REFERENCES frequency_lists(id),
+	rank INTEGER NOT NULL,
+	word TEXT NOT NULL,
+	PRIMARY KEY (list_id, rank)
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	refresh_token_hash TEXT NOT NULL UNIQUE,
+	previous_token_hash TEXT,
+	user_agent TEXT DEFAULT '',
+	ip TEXT DEFAULT '',
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	last_used_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMPTZ NOT NULL,
+	revoked_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS access_tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	scopes TEXT NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMPTZ,
+	last_used_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_id ON flashcards(user_id);
+CREATE INDEX IF NOT EXISTS idx_next_review ON flashcards(next_review);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_previous_token ON sessions(previous_token_hash);
@@ -0,0 +1,2 @@
+-- The normalized timestamps are valid in the old schema as well.
+SELECT 1;
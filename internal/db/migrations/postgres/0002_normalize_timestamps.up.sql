@@ -0,0 +1,3 @@
+-- PostgreSQL stores TIMESTAMPTZ values, which are always in one format.
+-- The migration exists to keep the versions of both dialects in step.
+SELECT 1;
@@ -0,0 +1,16 @@
+-- Deleting a user or a frequency list now deletes the rows that refer to it.
+
+ALTER TABLE recovery_codes DROP CONSTRAINT IF EXISTS recovery_codes_user_id_fkey,
+	ADD CONSTRAINT recovery_codes_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE user_identities DROP CONSTRAINT IF EXISTS user_identities_user_id_fkey,
+	ADD CONSTRAINT user_identities_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_user_id_fkey,
+	ADD CONSTRAINT user_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE flashcards DROP CONSTRAINT IF EXISTS flashcards_user_id_fkey,
+	ADD CONSTRAINT flashcards_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE frequency_words DROP CONSTRAINT IF EXISTS frequency_words_list_id_fkey,
+	ADD CONSTRAINT frequency_words_list_id_fkey FOREIGN KEY (list_id) REFERENCES frequency_lists(id) ON DELETE CASCADE;
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS sessions_user_id_fkey,
+	ADD CONSTRAINT sessions_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE access_tokens DROP CONSTRAINT IF EXISTS access_tokens_user_id_fkey,
+	ADD CONSTRAINT access_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+-- Without the column a trashed card would come back, so the trash is
+-- emptied first.
+DELETE FROM flashcards WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_flashcards_deleted_at;
+ALTER TABLE flashcards DROP COLUMN deleted_at;
@@ -0,0 +1,19 @@
+-- Every edit of a card is stored as a revision: the card's fields after the
+-- edit, who made it and when. History starts with the cards as they are now.
+
+CREATE TABLE IF NOT EXISTS card_revisions (
+	id SERIAL PRIMARY KEY,
+	card_id INTEGER NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	word TEXT NOT NULL,
+	meaning TEXT NOT NULL,
+	example TEXT NOT NULL DEFAULT '',
+	tags TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_revisions_card_id ON card_revisions(card_id);
+
+INSERT INTO card_revisions (card_id, user_id, word, meaning, example, tags, created_at)
+SELECT id, user_id, word, meaning, COALESCE(example, ''), COALESCE(tags, ''), CURRENT_TIMESTAMP
+FROM flashcards;
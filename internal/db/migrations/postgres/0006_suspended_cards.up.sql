@@ -0,0 +1,2 @@
+-- Suspended cards keep their schedule but are left out of reviews.
+ALTER TABLE flashcards ADD COLUMN suspended BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,7 @@
+-- version counts the changes of a card and is sent to clients as its ETag,
+-- so that an edit based on an outdated copy can be refused.
+
+ALTER TABLE flashcards ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE flashcards ADD COLUMN updated_at TIMESTAMPTZ;
+UPDATE flashcards SET updated_at = COALESCE(created_at, CURRENT_TIMESTAMP);
+ALTER TABLE flashcards ALTER COLUMN updated_at SET NOT NULL;
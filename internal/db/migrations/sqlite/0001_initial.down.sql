@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS access_tokens;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS frequency_words;
+DROP TABLE IF EXISTS frequency_lists;
+DROP TABLE IF EXISTS translations;
+DROP TABLE IF EXISTS flashcards;
+DROP TABLE IF EXISTS user_tokens;
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,63 @@
+-- Rewrites every timestamp as 2006-01-02T15:04:05Z in UTC. Older rows may
+-- hold SQLite's CURRENT_TIMESTAMP format, other offsets, fractional seconds
+-- or unix seconds, which break text comparisons such as next_review < ?.
+-- Text SQLite cannot parse is left alone and reported as an error on read.
+
+UPDATE users SET email_verified_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', email_verified_at), email_verified_at) WHERE typeof(email_verified_at) = 'text';
+UPDATE users SET email_verified_at = strftime('%Y-%m-%dT%H:%M:%SZ', email_verified_at, 'unixepoch') WHERE typeof(email_verified_at) IN ('integer', 'real');
+UPDATE users SET totp_enabled_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', totp_enabled_at), totp_enabled_at) WHERE typeof(totp_enabled_at) = 'text';
+UPDATE users SET totp_enabled_at = strftime('%Y-%m-%dT%H:%M:%SZ', totp_enabled_at, 'unixepoch') WHERE typeof(totp_enabled_at) IN ('integer', 'real');
+UPDATE users SET disabled_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', disabled_at), disabled_at) WHERE typeof(disabled_at) = 'text';
+UPDATE users SET disabled_at = strftime('%Y-%m-%dT%H:%M:%SZ', disabled_at, 'unixepoch') WHERE typeof(disabled_at) IN ('integer', 'real');
+
+UPDATE recovery_codes SET used_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', used_at), used_at) WHERE typeof(used_at) = 'text';
+UPDATE recovery_codes SET used_at = strftime('%Y-%m-%dT%H:%M:%SZ', used_at, 'unixepoch') WHERE typeof(used_at) IN ('integer', 'real');
+
+UPDATE user_identities SET created_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', created_at), created_at) WHERE typeof(created_at) = 'text';
+UPDATE user_identities SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at, 'unixepoch') WHERE typeof(created_at) IN ('integer', 'real');
+
+UPDATE user_tokens SET created_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', created_at), created_at) WHERE typeof(created_at) = 'text';
+UPDATE user_tokens SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at, 'unixepoch') WHERE typeof(created_at) IN ('integer', 'real');
+UPDATE user_tokens SET expires_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', expires_at), expires_at) WHERE typeof(expires_at) = 'text';
+UPDATE user_tokens SET expires_at = strftime('%Y-%m-%dT%H:%M:%SZ', expires_at, 'unixepoch') WHERE typeof(expires_at) IN ('integer', 'real');
+UPDATE user_tokens SET used_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', used_at), used_at) WHERE typeof(used_at) = 'text';
+UPDATE user_tokens SET used_at = strftime('%Y-%m-%dT%H:%M:%SZ', used_at, 'unixepoch') WHERE typeof(used_at) IN ('integer', 'real');
+
+UPDATE flashcards SET next_review = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', next_review), next_review) WHERE typeof(next_review) = 'text';
+UPDATE flashcards SET next_review = strftime('%Y-%m-%dT%H:%M:%SZ', next_review, 'unixepoch') WHERE typeof(next_review) IN ('integer', 'real');
+UPDATE flashcards SET created_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', created_at), created_at) WHERE typeof(created_at) = 'text';
+UPDATE flashcards SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at, 'unixepoch') WHERE typeof(created_at) IN ('integer', 'real');
+
+UPDATE translations SET created_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', created_at), created_at) WHERE typeof(created_at) = 'text';
+UPDATE translations SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at, 'unixepoch') WHERE typeof(created_at) IN ('integer', 'real');
+
+UPDATE frequency_lists SET created_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', created_at), created_at) WHERE typeof(created_at) = 'text';
+UPDATE frequency_lists SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at, 'unixepoch') WHERE typeof(created_at) IN ('integer', 'real');
+
+UPDATE sessions SET created_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', created_at), created_at) WHERE typeof(created_at) = 'text';
+UPDATE sessions SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at, 'unixepoch') WHERE typeof(created_at) IN ('integer', 'real');
+UPDATE sessions SET last_used_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', last_used_at), last_used_at) WHERE typeof(last_used_at) = 'text';
+UPDATE sessions SET last_used_at = strftime('%Y-%m-%dT%H:%M:%SZ', last_used_at, 'unixepoch') WHERE typeof(last_used_at) IN ('integer', 'real');
+UPDATE sessions SET expires_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', expires_at), expires_at) WHERE typeof(expires_at) = 'text';
+UPDATE sessions SET expires_at = strftime('%Y-%m-%dT%H:%M:%SZ', expires_at, 'unixepoch') WHERE typeof(expires_at) IN ('integer', 'real');
+UPDATE sessions SET revoked_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', revoked_at), revoked_at) WHERE typeof(revoked_at) = 'text';
+UPDATE sessions SET revoked_at = strftime('%Y-%m-%dT%H:%M:%SZ', revoked_at, 'unixepoch') WHERE typeof(revoked_at) IN ('integer', 'real');
+
+UPDATE access_tokens SET created_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', created_at), created_at) WHERE typeof(created_at) = 'text';
+UPDATE access_tokens SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at, 'unixepoch') WHERE typeof(created_at) IN ('integer', 'real');
+UPDATE access_tokens SET expires_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', expires_at), expires_at) WHERE typeof(expires_at) = 'text';
+UPDATE access_tokens SET expires_at = strftime('%Y-%m-%dT%H:%M:%SZ', expires_at, 'unixepoch') WHERE typeof(expires_at) IN ('integer', 'real');
+UPDATE access_tokens SET last_used_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', last_used_at), last_used_at) WHERE typeof(last_used_at) = 'text';
+UPDATE access_tokens SET last_used_at = strftime('%Y-%m-%dT%H:%M:%SZ', last_used_at, 'unixepoch') WHERE typeof(last_used_at) IN ('integer', 'real');
+UPDATE access_tokens SET revoked_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', revoked_at), revoked_at) WHERE typeof(revoked_at) = 'text';
+UPDATE access_tokens SET revoked_at = strftime('%Y-%m-%dT%H:%M:%SZ', revoked_at, 'unixepoch') WHERE typeof(revoked_at) IN ('integer', 'real');
+
+UPDATE schema_migrations SET applied_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', applied_at), applied_at) WHERE typeof(applied_at) = 'text';
+UPDATE schema_migrations SET applied_at = strftime('%Y-%m-%dT%H:%M:%SZ', applied_at, 'unixepoch') WHERE typeof(applied_at) IN ('integer', 'real');
+
+-- Cards whose dates were lost (NULL, unparseable or the zero date left by
+-- failed parses) are due today instead of never coming due correctly.
+UPDATE flashcards SET next_review = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+	WHERE next_review IS NULL OR next_review < '1970-01-01T00:00:00Z' OR strftime('%Y-%m-%dT%H:%M:%SZ', next_review) IS NULL;
+UPDATE flashcards SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+	WHERE created_at IS NULL OR created_at < '1970-01-01T00:00:00Z' OR strftime('%Y-%m-%dT%H:%M:%SZ', created_at) IS NULL;
@@ -0,0 +1,108 @@
+-- Rebuilds the child tables with the foreign keys of 0001.
+
+CREATE TABLE recovery_codes_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	code_hash TEXT NOT NULL,
+	used_at DATETIME,
+	FOREIGN KEY (user_id) REFERENCES users(id)
+);
+INSERT INTO recovery_codes_new (id, user_id, code_hash, used_at) SELECT id, user_id, code_hash, used_at FROM recovery_codes;
+DROP TABLE recovery_codes;
+ALTER TABLE recovery_codes_new RENAME TO recovery_codes;
+
+CREATE TABLE user_identities_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	issuer TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	email TEXT DEFAULT '',
+	created_at DATETIME DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	UNIQUE (issuer, subject),
+	FOREIGN KEY (user_id) REFERENCES users(id)
+);
+INSERT INTO user_identities_new (id, user_id, issuer, subject, email, created_at) SELECT id, user_id, issuer, subject, email, created_at FROM user_identities;
+DROP TABLE user_identities;
+ALTER TABLE user_identities_new RENAME TO user_identities;
+
+CREATE TABLE user_tokens_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	purpose TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	data TEXT DEFAULT '',
+	created_at DATETIME DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	expires_at DATETIME NOT NULL,
+	used_at DATETIME,
+	FOREIGN KEY (user_id) REFERENCES users(id)
+);
+INSERT INTO user_tokens_new (id, user_id, purpose, token_hash, data, created_at, expires_at, used_at) SELECT id, user_id, purpose, token_hash, data, created_at, expires_at, used_at FROM user_tokens;
+DROP TABLE user_tokens;
+ALTER TABLE user_tokens_new RENAME TO user_tokens;
+
+CREATE TABLE flashcards_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	word TEXT NOT NULL,
+	meaning TEXT NOT NULL,
+	example TEXT,
+	tags TEXT DEFAULT '',
+	next_review DATETIME DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	interval INTEGER DEFAULT 1,
+	repetitions INTEGER DEFAULT 0,
+	ef REAL DEFAULT 2.5,
+	created_at DATETIME DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	FOREIGN KEY (user_id) REFERENCES users(id)
+);
+INSERT INTO flashcards_new (id, user_id, word, meaning, example, tags, next_review, interval, repetitions, ef, created_at) SELECT id, user_id, word, meaning, example, tags, next_review, interval, repetitions, ef, created_at FROM flashcards;
+DROP TABLE flashcards;
+ALTER TABLE flashcards_new RENAME TO flashcards;
+
+CREATE TABLE frequency_words_new (
+	list_id INTEGER NOT NULL,
+	rank INTEGER NOT NULL,
+	word TEXT NOT NULL,
+	PRIMARY KEY (list_id, rank),
+	FOREIGN KEY (list_id) REFERENCES frequency_lists(id)
+);
+INSERT INTO frequency_words_new (list_id, rank, word) SELECT list_id, rank, word FROM frequency_words;
+DROP TABLE frequency_words;
+ALTER TABLE frequency_words_new RENAME TO frequency_words;
+
+CREATE TABLE sessions_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	refresh_token_hash TEXT NOT NULL UNIQUE,
+	previous_token_hash TEXT,
+	user_agent TEXT DEFAULT '',
+	ip TEXT DEFAULT '',
+	created_at DATETIME DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	last_used_at DATETIME DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	expires_at DATETIME NOT NULL,
+	revoked_at DATETIME,
+	FOREIGN KEY (user_id) REFERENCES users(id)
+);
+INSERT INTO sessions_new (id, user_id, refresh_token_hash, previous_token_hash, user_agent, ip, created_at, last_used_at, expires_at, revoked_at) SELECT id, user_id, refresh_token_hash, previous_token_hash, user_agent, ip, created_at, last_used_at, expires_at, revoked_at FROM sessions;
+DROP TABLE sessions;
+ALTER TABLE sessions_new RENAME TO sessions;
+
+CREATE TABLE access_tokens_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	scopes TEXT NOT NULL,
+	created_at DATETIME DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	expires_at DATETIME,
+	last_used_at DATETIME,
+	revoked_at DATETIME,
+	FOREIGN KEY (user_id) REFERENCES users(id)
+);
+INSERT INTO access_tokens_new (id, user_id, name, token_hash, scopes, created_at, expires_at, last_used_at, revoked_at) SELECT id, user_id, name, token_hash, scopes, created_at, expires_at, last_used_at, revoked_at FROM access_tokens;
+DROP TABLE access_tokens;
+ALTER TABLE access_tokens_new RENAME TO access_tokens;
+
+CREATE INDEX IF NOT EXISTS idx_user_id ON flashcards(user_id);
+CREATE INDEX IF NOT EXISTS idx_next_review ON flashcards(next_review);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_previous_token ON sessions(previous_token_hash);
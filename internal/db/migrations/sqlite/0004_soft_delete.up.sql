@@ -0,0 +1,5 @@
+-- Deleted cards go to the trash: deleted_at is set instead of removing the
+-- row, and the card is purged once the trash retention has passed.
+
+ALTER TABLE flashcards ADD COLUMN deleted_at DATETIME;
+CREATE INDEX IF NOT EXISTS idx_flashcards_deleted_at ON flashcards(deleted_at);
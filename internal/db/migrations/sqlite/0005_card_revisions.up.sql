@@ -0,0 +1,21 @@
+-- Every edit of a card is stored as a revision: the card's fields after the
+-- edit, who made it and when. History starts with the cards as they are now.
+
+CREATE TABLE IF NOT EXISTS card_revisions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	card_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	word TEXT NOT NULL,
+	meaning TEXT NOT NULL,
+	example TEXT NOT NULL DEFAULT '',
+	tags TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (card_id) REFERENCES flashcards(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_revisions_card_id ON card_revisions(card_id);
+
+INSERT INTO card_revisions (card_id, user_id, word, meaning, example, tags, created_at)
+SELECT id, user_id, word, meaning, COALESCE(example, ''), COALESCE(tags, ''), strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+FROM flashcards;
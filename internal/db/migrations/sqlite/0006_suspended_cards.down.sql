@@ -0,0 +1 @@
+ALTER TABLE flashcards DROP COLUMN suspended;
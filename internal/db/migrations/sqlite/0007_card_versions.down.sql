@@ -0,0 +1,2 @@
+ALTER TABLE flashcards DROP COLUMN updated_at;
+ALTER TABLE flashcards DROP COLUMN version;
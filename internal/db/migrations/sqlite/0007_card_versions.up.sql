@@ -0,0 +1,6 @@
+-- version counts the changes of a card and is sent to clients as its ETag,
+-- so that an edit based on an outdated copy can be refused.
+
+ALTER TABLE flashcards ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE flashcards ADD COLUMN updated_at DATETIME;
+UPDATE flashcards SET updated_at = COALESCE(created_at, strftime('%Y-%m-%dT%H:%M:%SZ', 'now'));
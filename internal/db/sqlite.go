@@ -0,0 +1,59 @@
+package db
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQLiteOptions are the pragmas every SQLite connection is opened with.
+type SQLiteOptions struct {
+	JournalMode string // e.g. WAL, which lets readers run alongside the writer
+	Synchronous string
+	BusyTimeout time.Duration
+	ForeignKeys bool
+	// ReadConns caps the read pool; 0 means unlimited.
+	ReadConns int
+}
+
+// sqliteDSN adds the pragmas to path as go-sqlite3 parameters, so that they
+// apply to every connection of the pool and not just the first. Read-only
+// connections refuse writes; the writer starts its transactions with BEGIN
+// IMMEDIATE, so a transaction that reads before writing waits for the lock
+// up front instead of failing with "database is locked" when it upgrades.
+func sqliteDSN(path string, opts SQLiteOptions, readOnly bool) string {
+	params := url.Values{}
+	if opts.JournalMode != "" {
+		params.Set("_journal_mode", opts.JournalMode)
+	}
+	if opts.Synchronous != "" {
+		params.Set("_synchronous", opts.Synchronous)
+	}
+	params.Set("_busy_timeout", strconv.FormatInt(opts.BusyTimeout.Milliseconds(), 10))
+	params.Set("_foreign_keys", strconv.FormatBool(opts.ForeignKeys))
+	if readOnly {
+		params.Set("_query_only", "true")
+	} else {
+		params.Set("_txlock", "immediate")
+	}
+
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + params.Encode()
+}
+
+// sqliteURI returns a file: URI for the SQLite file at path with params.
+// The path is escaped, so a ? or # in a file name is not taken for the
+// start of the query or fragment.
+func sqliteURI(path string, params url.Values) string {
+	return "file:" + (&url.URL{Path: path}).EscapedPath() + "?" + params.Encode()
+}
+
+// inMemory reports whether path is an in-memory database, which each
+// connection would see as a separate, empty database.
+func inMemory(path string) bool {
+	return path == ":memory:" || strings.Contains(path, "mode=memory")
+}
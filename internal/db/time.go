@@ -0,0 +1,93 @@
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// TimeFormat is how timestamps are stored: UTC with second precision. The
+// fixed width keeps SQLite's text comparisons in time order.
+const TimeFormat = "2006-01-02T15:04:05Z"
+
+// textTimeFormats are the text layouts accepted when reading, including
+// SQLite's CURRENT_TIMESTAMP used by older databases.
+var textTimeFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// Time is a timestamp column. As a query argument it is written in
+// TimeFormat; PostgreSQL stores it as TIMESTAMPTZ. Scanning accepts the
+// driver's native time, text in any of textTimeFormats and unix seconds,
+// and fails on anything else rather than returning the zero time.
+type Time time.Time
+
+func (t Time) Value() (driver.Value, error) {
+	return time.Time(t).UTC().Format(TimeFormat), nil
+}
+
+func (t *Time) Scan(src any) error {
+	parsed, err := parseTime(src)
+	if err != nil {
+		return err
+	}
+	*t = Time(parsed)
+	return nil
+}
+
+// OptionalTime scans a nullable timestamp column into *dest, which is left
+// nil for NULL.
+func OptionalTime(dest **time.Time) sql.Scanner {
+	return optionalTime{dest}
+}
+
+type optionalTime struct {
+	dest **time.Time
+}
+
+func (o optionalTime) Scan(src any) error {
+	if src == nil {
+		*o.dest = nil
+		return nil
+	}
+	t, err := parseTime(src)
+	if err != nil {
+		return err
+	}
+	*o.dest = &t
+	return nil
+}
+
+func parseTime(src any) (time.Time, error) {
+	switch v := src.(type) {
+	case time.Time:
+		// The SQLite driver turns text it cannot parse into the zero time.
+		if v.IsZero() {
+			return v, fmt.Errorf("invalid timestamp")
+		}
+		return v.UTC(), nil
+	case int64:
+		return time.Unix(v, 0).UTC(), nil
+	case []byte:
+		return parseTimeText(string(v))
+	case string:
+		return parseTimeText(v)
+	case nil:
+		return time.Time{}, fmt.Errorf("timestamp is NULL")
+	default:
+		return time.Time{}, fmt.Errorf("unsupported timestamp type %T", src)
+	}
+}
+
+func parseTimeText(s string) (time.Time, error) {
+	for _, layout := range textTimeFormats {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+}
@@ -0,0 +1,117 @@
+package dictionary
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const dictdAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// Dictd reads dictionaries in the dictd .index/.dict(.dz) format.
+type Dictd struct {
+	name  string
+	index map[string][]location
+	data  *dictData
+}
+
+// OpenDictd loads the dictionary described by the given .index file.
+func OpenDictd(indexPath string) (*Dictd, error) {
+	f, err := os.Open(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	base := strings.TrimSuffix(indexPath, ".index")
+	d := &Dictd{name: base, index: make(map[string][]location)}
+
+	var shortName *location
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 3 {
+			continue
+		}
+		offset, err := decodeDictdNumber(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("corrupted index entry %q: %w", fields[0], err)
+		}
+		size, err := decodeDictdNumber(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("corrupted index entry %q: %w", fields[0], err)
+		}
+
+		loc := location{word: fields[0], offset: offset, size: size}
+		// Service entries describe the database itself.
+		if strings.HasPrefix(loc.word, "00database") || strings.HasPrefix(loc.word, "00-database") {
+			if strings.Contains(loc.word, "short") {
+				shortName = &loc
+			}
+			continue
+		}
+		key := normalize(loc.word)
+		d.index[key] = append(d.index[key], loc)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if d.data, err = openDictData(base + ".dict"); err != nil {
+		return nil, fmt.Errorf("failed to open dict data: %w", err)
+	}
+
+	if shortName != nil {
+		if raw, err := d.data.read(shortName.offset, shortName.size); err == nil {
+			name := strings.TrimSpace(string(raw))
+			name = strings.TrimSpace(strings.TrimPrefix(name, shortName.word))
+			if name != "" {
+				d.name = name
+			}
+		}
+	}
+	return d, nil
+}
+
+func (d *Dictd) Name() string {
+	return d.name
+}
+
+func (d *Dictd) Lookup(word string) ([]Entry, error) {
+	var entries []Entry
+	for _, loc := range d.index[normalize(word)] {
+		raw, err := d.data.read(loc.offset, loc.size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read article %q: %w", loc.word, err)
+		}
+		// Articles usually repeat the headword on their first line.
+		text := strings.TrimSpace(string(raw))
+		if first, rest, ok := strings.Cut(text, "\n"); ok && normalize(first) == normalize(loc.word) {
+			text = strings.TrimSpace(rest)
+		}
+		entries = append(entries, Entry{
+			Dictionary: d.name,
+			Word:       loc.word,
+			Definition: text,
+		})
+	}
+	return entries, nil
+}
+
+func (d *Dictd) Close() error {
+	return d.data.Close()
+}
+
+func decodeDictdNumber(s string) (int64, error) {
+	var n int64
+	for _, c := range s {
+		i := strings.IndexRune(dictdAlphabet, c)
+		if i < 0 {
+			return 0, fmt.Errorf("invalid base64 digit %q", c)
+		}
+		n = n*64 + int64(i)
+	}
+	return n, nil
+}
@@ -0,0 +1,174 @@
+package dictionary
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Entry is a single article found in one of the loaded dictionaries.
+type Entry struct {
+	Dictionary string `json:"dictionary"`
+	Word       string `json:"word"`
+	Definition string `json:"definition"`
+}
+
+// Dictionary is an offline word lookup source (StarDict, dictd, ...).
+type Dictionary interface {
+	Name() string
+	Lookup(word string) ([]Entry, error)
+	Close() error
+}
+
+var (
+	mu    sync.RWMutex
+	dicts []Dictionary
+)
+
+// InitDictionaries loads every StarDict (.ifo) and dictd (.index) dictionary
+// found in the given files or directories. Broken dictionaries are skipped.
+func InitDictionaries(paths []string) error {
+	var loaded []Dictionary
+	for _, root := range paths {
+		root = strings.TrimSpace(root)
+		if root == "" {
+			continue
+		}
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+
+			var dict Dictionary
+			var openErr error
+			switch {
+			case strings.HasSuffix(path, ".ifo"):
+				dict, openErr = OpenStarDict(path)
+			case strings.HasSuffix(path, ".index"):
+				dict, openErr = OpenDictd(path)
+			default:
+				return nil
+			}
+			if openErr != nil {
+				log.Printf("Skipping dictionary %s: %v", path, openErr)
+				return nil
+			}
+			loaded = append(loaded, dict)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to load dictionaries from %s: %w", root, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, d := range dicts {
+		d.Close()
+	}
+	dicts = loaded
+	if len(dicts) > 0 {
+		log.Printf("Loaded %d dictionaries.", len(dicts))
+	}
+	return nil
+}
+
+// Enabled reports whether at least one dictionary is loaded.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(dicts) > 0
+}
+
+// Lookup searches all loaded dictionaries, case-insensitively.
+func Lookup(word string) ([]Entry, error) {
+	word = strings.TrimSpace(word)
+	if word == "" {
+		return nil, nil
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var entries []Entry
+	for _, d := range dicts {
+		found, err := d.Lookup(word)
+		if err != nil {
+			return nil, fmt.Errorf("lookup in %s failed: %w", d.Name(), err)
+		}
+		entries = append(entries, found...)
+	}
+	return entries, nil
+}
+
+// Meaning returns the first definition for word, or "" if nothing is found.
+func Meaning(word string) (string, error) {
+	entries, err := Lookup(word)
+	if err != nil || len(entries) == 0 {
+		return "", err
+	}
+	return entries[0].Definition, nil
+}
+
+// dictData gives random access to an uncompressed or dictzip'ed .dict file.
+type dictData struct {
+	file *os.File
+	data []byte
+	size int64
+}
+
+func openDictData(path string) (*dictData, error) {
+	if _, err := os.Stat(path); err == nil {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &dictData{file: f, size: info.Size()}, nil
+	}
+
+	// dictzip files are plain gzip streams, so load them into memory.
+	data, err := readGzip(path + ".dz")
+	if err != nil {
+		return nil, err
+	}
+	return &dictData{data: data, size: int64(len(data))}, nil
+}
+
+// read returns size bytes at offset. Both come from the index, so they are
+// checked against the data before anything is allocated.
+func (d *dictData) read(offset, size int64) ([]byte, error) {
+	if offset < 0 || size < 0 || offset > d.size-size {
+		return nil, fmt.Errorf("entry out of range")
+	}
+	if d.file != nil {
+		buf := make([]byte, size)
+		if _, err := d.file.ReadAt(buf, offset); err != nil {
+			return nil, err
+		}
+		return buf, nil
+	}
+	return d.data[offset : offset+size], nil
+}
+
+func (d *dictData) Close() error {
+	if d.file != nil {
+		return d.file.Close()
+	}
+	return nil
+}
+
+func normalize(word string) string {
+	return strings.ToLower(strings.TrimSpace(word))
+}
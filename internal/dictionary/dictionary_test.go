@@ -0,0 +1,167 @@
+package dictionary_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Danyarbrg/flashCards/internal/dictionary"
+)
+
+// copyFixture copies the testdata files of a dictionary into a temporary
+// directory, where a test may change them, and returns the new base path.
+func copyFixture(t *testing.T, base string, exts ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, ext := range exts {
+		data, err := os.ReadFile(filepath.Join("testdata", base+ext))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, base+ext), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, base)
+}
+
+func definitions(entries []dictionary.Entry) []string {
+	var defs []string
+	for _, e := range entries {
+		defs = append(defs, e.Definition)
+	}
+	return defs
+}
+
+func TestStarDict(t *testing.T) {
+	d, err := dictionary.OpenStarDict("testdata/stardict.ifo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if d.Name() != "Test StarDict" {
+		t.Errorf("name = %q", d.Name())
+	}
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"apple", "a round fruit"},
+		{" BOOK ", "a written work|to reserve"},
+		{"pear", ""},
+	}
+	for _, tt := range tests {
+		entries, err := d.Lookup(tt.word)
+		if err != nil {
+			t.Fatalf("lookup %q: %v", tt.word, err)
+		}
+		if got := strings.Join(definitions(entries), "|"); got != tt.want {
+			t.Errorf("lookup %q = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestDictd(t *testing.T) {
+	d, err := dictionary.OpenDictd("testdata/dictd.index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if d.Name() != "Test dictd" {
+		t.Errorf("name = %q", d.Name())
+	}
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"cat", "a small animal"},
+		{"Apple", "a round fruit"},
+		{"dog", ""},
+		{"00databaseshort", ""},
+	}
+	for _, tt := range tests {
+		entries, err := d.Lookup(tt.word)
+		if err != nil {
+			t.Fatalf("lookup %q: %v", tt.word, err)
+		}
+		if got := strings.Join(definitions(entries), "|"); got != tt.want {
+			t.Errorf("lookup %q = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if err := dictionary.InitDictionaries([]string{"testdata"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dictionary.InitDictionaries(nil) })
+
+	if !dictionary.Enabled() {
+		t.Fatal("no dictionary loaded")
+	}
+	entries, err := dictionary.Lookup("apple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("entries = %+v, want one from each dictionary", entries)
+	}
+	if meaning, err := dictionary.Meaning("pear"); err != nil || meaning != "" {
+		t.Errorf("meaning of a missing word = %q (err %v)", meaning, err)
+	}
+}
+
+func TestTruncatedStarDictIndex(t *testing.T) {
+	base := copyFixture(t, "stardict", ".ifo", ".idx", ".dict")
+	idx, err := os.ReadFile(base + ".idx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(base+".idx", idx[:len(idx)-3], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dictionary.OpenStarDict(base + ".ifo"); err == nil {
+		t.Error("opened a dictionary with a truncated index")
+	}
+}
+
+// Entries pointing outside the data fail the lookup without reading, or
+// allocating, what the index claims.
+func TestEntryOutOfRange(t *testing.T) {
+	base := copyFixture(t, "stardict", ".ifo", ".idx", ".dict")
+	if err := os.Truncate(base+".dict", 20); err != nil {
+		t.Fatal(err)
+	}
+	star, err := dictionary.OpenStarDict(base + ".ifo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer star.Close()
+	if _, err := star.Lookup("apple"); err != nil {
+		t.Errorf("lookup of an entry within the data: %v", err)
+	}
+	if _, err := star.Lookup("book"); err == nil {
+		t.Error("lookup of an entry past the end of the data succeeded")
+	}
+
+	base = copyFixture(t, "dictd", ".index", ".dict")
+	index := "huge\tA\t/////////\n" + // 2^54-1 bytes
+		"negative\tA\t///////////\n" + // overflows int64
+		"past\t////\tB\n"
+	if err := os.WriteFile(base+".index", []byte(index), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dictd, err := dictionary.OpenDictd(base + ".index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dictd.Close()
+	for _, word := range []string{"huge", "negative", "past"} {
+		if _, err := dictd.Lookup(word); err == nil {
+			t.Errorf("lookup of %q succeeded", word)
+		}
+	}
+}
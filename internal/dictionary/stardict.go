@@ -0,0 +1,235 @@
+package dictionary
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+type location struct {
+	word   string
+	offset int64
+	size   int64
+}
+
+// StarDict reads dictionaries in the StarDict .ifo/.idx/.dict format.
+type StarDict struct {
+	name         string
+	typeSequence string
+	index        map[string][]location
+	data         *dictData
+}
+
+// OpenStarDict loads the dictionary described by the given .ifo file.
+// The index is kept in memory, articles are read on demand.
+func OpenStarDict(ifoPath string) (*StarDict, error) {
+	info, err := readIfo(ifoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimSuffix(ifoPath, ".ifo")
+	idx, err := readMaybeGzip(base + ".idx")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	offsetBits := 32
+	if info["idxoffsetbits"] == "64" {
+		offsetBits = 64
+	}
+	index, err := parseStarDictIndex(idx, offsetBits)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := openDictData(base + ".dict")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dict data: %w", err)
+	}
+
+	name := info["bookname"]
+	if name == "" {
+		name = base
+	}
+	return &StarDict{
+		name:         name,
+		typeSequence: info["sametypesequence"],
+		index:        index,
+		data:         data,
+	}, nil
+}
+
+func (s *StarDict) Name() string {
+	return s.name
+}
+
+func (s *StarDict) Lookup(word string) ([]Entry, error) {
+	var entries []Entry
+	for _, loc := range s.index[normalize(word)] {
+		raw, err := s.data.read(loc.offset, loc.size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read article %q: %w", loc.word, err)
+		}
+		entries = append(entries, Entry{
+			Dictionary: s.name,
+			Word:       loc.word,
+			Definition: s.decode(raw),
+		})
+	}
+	return entries, nil
+}
+
+func (s *StarDict) Close() error {
+	return s.data.Close()
+}
+
+func readIfo(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() || !strings.HasPrefix(scanner.Text(), "StarDict's dict ifo file") {
+		return nil, fmt.Errorf("not a StarDict .ifo file")
+	}
+
+	info := make(map[string]string)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if ok {
+			info[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return info, scanner.Err()
+}
+
+func parseStarDictIndex(idx []byte, offsetBits int) (map[string][]location, error) {
+	index := make(map[string][]location)
+	for len(idx) > 0 {
+		end := bytes.IndexByte(idx, 0)
+		if end < 0 {
+			return nil, fmt.Errorf("corrupted index: unterminated word")
+		}
+		word := string(idx[:end])
+		idx = idx[end+1:]
+
+		var offset int64
+		if offsetBits == 64 {
+			if len(idx) < 12 {
+				return nil, fmt.Errorf("corrupted index near %q", word)
+			}
+			offset = int64(binary.BigEndian.Uint64(idx))
+			idx = idx[8:]
+		} else {
+			if len(idx) < 8 {
+				return nil, fmt.Errorf("corrupted index near %q", word)
+			}
+			offset = int64(binary.BigEndian.Uint32(idx))
+			idx = idx[4:]
+		}
+		size := int64(binary.BigEndian.Uint32(idx))
+		idx = idx[4:]
+
+		key := normalize(word)
+		index[key] = append(index[key], location{word: word, offset: offset, size: size})
+	}
+	return index, nil
+}
+
+// decode turns raw article data into plain text. Only the textual field
+// types are kept; resources like sounds and pictures are dropped.
+func (s *StarDict) decode(raw []byte) string {
+	var parts []string
+	if s.typeSequence != "" {
+		for i, t := range s.typeSequence {
+			last := i == len(s.typeSequence)-1
+			var field []byte
+			field, raw = nextField(byte(t), raw, last)
+			if text := fieldText(byte(t), field); text != "" {
+				parts = append(parts, text)
+			}
+		}
+	} else {
+		for len(raw) > 0 {
+			t := raw[0]
+			var field []byte
+			field, raw = nextField(t, raw[1:], false)
+			if text := fieldText(t, field); text != "" {
+				parts = append(parts, text)
+			}
+		}
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
+
+func nextField(t byte, raw []byte, last bool) ([]byte, []byte) {
+	if last {
+		return raw, nil
+	}
+	if t >= 'a' && t <= 'z' {
+		end := bytes.IndexByte(raw, 0)
+		if end < 0 {
+			return raw, nil
+		}
+		return raw[:end], raw[end+1:]
+	}
+	if len(raw) < 4 {
+		return nil, nil
+	}
+	size := int(binary.BigEndian.Uint32(raw))
+	raw = raw[4:]
+	if size > len(raw) {
+		size = len(raw)
+	}
+	return raw[:size], raw[size:]
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+func fieldText(t byte, field []byte) string {
+	switch t {
+	case 'm', 'l', 't', 'y':
+		return strings.TrimSpace(string(field))
+	case 'h', 'g', 'x':
+		return strings.TrimSpace(html.UnescapeString(tagPattern.ReplaceAllString(string(field), "")))
+	default:
+		return ""
+	}
+}
+
+// readMaybeGzip reads path, falling back to a gzip'ed path+".gz".
+func readMaybeGzip(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return data, nil
+	}
+	if _, statErr := os.Stat(path + ".gz"); statErr != nil {
+		return nil, err
+	}
+	return readGzip(path + ".gz")
+}
+
+func readGzip(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,140 @@
+package frequency
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/Danyarbrg/flashCards/internal/models"
+)
+
+// ErrInvalidList is returned by Import for data that is not a usable
+// frequency list.
+var ErrInvalidList = errors.New("invalid frequency list")
+
+// Report describes how much of a frequency list a user's cards cover.
+type Report struct {
+	List            string                 `json:"list"`
+	Language        string                 `json:"language"`
+	WordCount       int                    `json:"word_count"`
+	Covered         int                    `json:"covered"`
+	CoveragePercent float64                `json:"coverage_percent"`
+	Missing         []models.FrequencyWord `json:"missing,omitempty"`
+}
+
+// Parse reads a frequency list, one entry per line, most frequent first.
+// Lines may carry extra columns such as a rank or a count ("1 the", "the 5243");
+// the first column containing a letter is taken as the word.
+func Parse(r io.Reader) ([]string, error) {
+	seen := make(map[string]bool)
+	var words []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, field := range strings.FieldsFunc(line, func(r rune) bool { return unicode.IsSpace(r) || r == ',' || r == ';' }) {
+			if strings.IndexFunc(field, unicode.IsLetter) == -1 {
+				continue
+			}
+			word := strings.ToLower(field)
+			if !seen[word] {
+				seen[word] = true
+				words = append(words, word)
+			}
+			break
+		}
+	}
+	return words, scanner.Err()
+}
+
+// LoadDir imports every .txt file in dir into lists, named after the
+// file. A "es-top5000.txt" file becomes list "es-top5000" with language "es".
+// Files whose content did not change since the last import are skipped.
+func LoadDir(lists models.FrequencyRepository, dir string) error {
+	if dir == "" {
+		return nil
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		language, _, _ := strings.Cut(name, "-")
+		if err := ImportFile(lists, name, language, path); err != nil {
+			return fmt.Errorf("failed to import %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// ImportFile imports a single frequency list file unless it is unchanged.
+func ImportFile(lists models.FrequencyRepository, name, language, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return Import(lists, name, language, data)
+}
+
+// Import stores data in lists as the list name unless it is unchanged.
+func Import(lists models.FrequencyRepository, name, language string, data []byte) error {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	existing, ok, err := lists.Get(name)
+	if err != nil {
+		return err
+	}
+	if ok && existing.SourceHash == hash {
+		return nil
+	}
+
+	words, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidList, err)
+	}
+	if len(words) == 0 {
+		return fmt.Errorf("%w: %q is empty", ErrInvalidList, name)
+	}
+	if err := lists.Import(name, language, hash, words); err != nil {
+		return err
+	}
+	log.Printf("Imported frequency list %s (%d words).", name, len(words))
+	return nil
+}
+
+// Coverage compares a list against the user's known words and returns up
+// to maxMissing of the most frequent words that have no card yet.
+func Coverage(list models.FrequencyList, words []models.FrequencyWord, known map[string]bool, maxMissing int) Report {
+	report := Report{
+		List:      list.Name,
+		Language:  list.Language,
+		WordCount: len(words),
+		Missing:   []models.FrequencyWord{},
+	}
+	for _, w := range words {
+		if known[w.Word] {
+			report.Covered++
+		} else if len(report.Missing) < maxMissing {
+			report.Missing = append(report.Missing, w)
+		}
+	}
+	if report.WordCount > 0 {
+		report.CoveragePercent = float64(report.Covered) * 100 / float64(report.WordCount)
+	}
+	return report
+}
@@ -0,0 +1,84 @@
+package lemma
+
+import "strings"
+
+// English is a rule-based lemmatizer for English: a table of irregular forms
+// plus suffix stripping for plurals, verb forms and comparatives.
+type English struct{}
+
+var englishIrregular = map[string]string{
+	"am": "be", "is": "be", "are": "be", "was": "be", "were": "be", "been": "be", "being": "be",
+	"has": "have", "had": "have", "does": "do", "did": "do", "done": "do",
+	"went": "go", "gone": "go", "ran": "run", "came": "come", "became": "become",
+	"saw": "see", "seen": "see", "took": "take", "taken": "take", "gave": "give", "given": "give",
+	"got": "get", "gotten": "get", "made": "make", "said": "say", "knew": "know", "known": "know",
+	"thought": "think", "brought": "bring", "bought": "buy", "caught": "catch", "taught": "teach",
+	"found": "find", "told": "tell", "felt": "feel", "left": "leave", "kept": "keep", "meant": "mean",
+	"met": "meet", "paid": "pay", "sold": "sell", "sent": "send", "spent": "spend", "stood": "stand",
+	"understood": "understand", "wrote": "write", "written": "write", "spoke": "speak", "spoken": "speak",
+	"ate": "eat", "eaten": "eat", "drank": "drink", "drunk": "drink", "drove": "drive", "driven": "drive",
+	"began": "begin", "begun": "begin", "broke": "break", "broken": "break", "chose": "choose", "chosen": "choose",
+	"fell": "fall", "fallen": "fall", "flew": "fly", "flown": "fly", "forgot": "forget", "forgotten": "forget",
+	"grew": "grow", "grown": "grow", "held": "hold", "led": "lead", "lost": "lose", "rode": "ride", "ridden": "ride",
+	"rose": "rise", "risen": "rise", "sang": "sing", "sung": "sing", "sat": "sit", "slept": "sleep",
+	"swam": "swim", "swum": "swim", "threw": "throw", "thrown": "throw", "woke": "wake", "woken": "wake",
+	"wore": "wear", "worn": "wear", "won": "win", "built": "build", "heard": "hear", "fought": "fight",
+	"children": "child", "men": "man", "women": "woman", "people": "person", "mice": "mouse",
+	"geese": "goose", "feet": "foot", "teeth": "tooth", "lives": "life", "wives": "wife", "knives": "knife",
+	"leaves": "leaf", "halves": "half", "wolves": "wolf", "shelves": "shelf", "oxen": "ox",
+	"better": "good", "best": "good", "worse": "bad", "worst": "bad", "further": "far", "farther": "far",
+}
+
+func (English) Lemmas(word string) []string {
+	w := Normalize(word)
+	seen := map[string]bool{w: true}
+	lemmas := []string{w}
+	add := func(l string) {
+		if len(l) >= 2 && !seen[l] {
+			seen[l] = true
+			lemmas = append(lemmas, l)
+		}
+	}
+
+	if base, ok := englishIrregular[w]; ok {
+		add(base)
+		return lemmas
+	}
+	// Multi-word expressions are compared as a whole.
+	if strings.Contains(w, " ") {
+		return lemmas
+	}
+
+	switch {
+	case strings.HasSuffix(w, "ies") && len(w) > 4:
+		add(w[:len(w)-3] + "y")
+	case strings.HasSuffix(w, "sses"), strings.HasSuffix(w, "shes"), strings.HasSuffix(w, "ches"),
+		strings.HasSuffix(w, "xes"), strings.HasSuffix(w, "zes"), strings.HasSuffix(w, "oes"):
+		add(w[:len(w)-2])
+	case strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") && !strings.HasSuffix(w, "us") && !strings.HasSuffix(w, "is"):
+		add(w[:len(w)-1])
+	}
+
+	for _, suffix := range []string{"ing", "ed", "est", "er"} {
+		if !strings.HasSuffix(w, suffix) || len(w) <= len(suffix)+2 {
+			continue
+		}
+		stem := w[:len(w)-len(suffix)]
+		if suffix == "ed" && strings.HasSuffix(stem, "i") {
+			add(stem[:len(stem)-1] + "y") // studied -> study
+			continue
+		}
+		if (suffix == "est" || suffix == "er") && strings.HasSuffix(stem, "i") {
+			add(stem[:len(stem)-1] + "y") // happier -> happy
+			continue
+		}
+		n := len(stem)
+		if n >= 2 && stem[n-1] == stem[n-2] && !strings.ContainsRune("aeiouls", rune(stem[n-1])) {
+			add(stem[:n-1]) // running -> run, stopped -> stop
+		}
+		add(stem)       // walked -> walk
+		add(stem + "e") // making -> make, used -> use
+		break
+	}
+	return lemmas
+}
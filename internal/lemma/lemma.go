@@ -0,0 +1,67 @@
+package lemma
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Lemmatizer maps an inflected word form to its possible dictionary forms.
+// Rule-based lemmatizers cannot always tell which rule applies, so several
+// candidates may be returned; the normalized word itself is always included.
+type Lemmatizer interface {
+	Lemmas(word string) []string
+}
+
+// New returns the lemmatizer for a language code. "none" disables
+// lemmatization so that only normalized forms are compared.
+func New(lang string) (Lemmatizer, error) {
+	switch strings.ToLower(lang) {
+	case "", "en", "english":
+		return English{}, nil
+	case "none":
+		return Identity{}, nil
+	default:
+		return nil, fmt.Errorf("no lemmatizer for language %q", lang)
+	}
+}
+
+// Normalize brings a word to a canonical form for comparison: Unicode NFC,
+// lower case, typographic apostrophes folded and inner whitespace collapsed.
+func Normalize(word string) string {
+	word = norm.NFC.String(word)
+	word = strings.Map(func(r rune) rune {
+		switch r {
+		case '’', '‘', 'ʼ':
+			return '\''
+		}
+		return unicode.ToLower(r)
+	}, word)
+	return strings.Join(strings.Fields(word), " ")
+}
+
+// Identity performs no lemmatization.
+type Identity struct{}
+
+func (Identity) Lemmas(word string) []string {
+	return []string{Normalize(word)}
+}
+
+var active Lemmatizer = English{}
+
+// InitLemmatizer selects the lemmatizer used for duplicate detection.
+func InitLemmatizer(lang string) error {
+	l, err := New(lang)
+	if err != nil {
+		return err
+	}
+	active = l
+	return nil
+}
+
+// Default returns the configured lemmatizer.
+func Default() Lemmatizer {
+	return active
+}
@@ -0,0 +1,73 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers transactional emails (verification, password reset).
+type Mailer interface {
+	Send(msg Message) error
+}
+
+var active Mailer = LogMailer{}
+
+// InitMailer selects how emails are delivered: "smtp" or "log" (the
+// default, for development and tests).
+func InitMailer(kind string, smtp SMTPMailer, logPath string) error {
+	switch strings.ToLower(kind) {
+	case "", "log":
+		active = LogMailer{Path: logPath}
+	case "smtp":
+		if smtp.Host == "" || smtp.From == "" {
+			return fmt.Errorf("SMTP_HOST and MAIL_FROM are required for the smtp mailer")
+		}
+		active = smtp
+	default:
+		return fmt.Errorf("unknown mailer %q", kind)
+	}
+	return nil
+}
+
+func Send(msg Message) error {
+	return active.Send(msg)
+}
+
+// LogMailer writes emails to a file, or to the application log when no
+// path is set, instead of delivering them.
+type LogMailer struct {
+	Path string
+}
+
+var logMu sync.Mutex
+
+func (m LogMailer) Send(msg Message) error {
+	entry := fmt.Sprintf("Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n---\n",
+		time.Now().UTC().Format(time.RFC1123Z), msg.To, msg.Subject, msg.Body)
+	if m.Path == "" {
+		log.Printf("Email (not sent):\n%s", entry)
+		return nil
+	}
+
+	logMu.Lock()
+	defer logMu.Unlock()
+	f, err := os.OpenFile(m.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open mail log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(entry); err != nil {
+		return fmt.Errorf("failed to write mail log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/db"
+)
+
+var ErrAccessTokenNotFound = errors.New("access token not found")
+
+// AccessToken is a named personal access token for scripts and integrations.
+// Only the hash of the token is stored.
+type AccessToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"-"`
+}
+
+func (t AccessToken) Active() bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || time.Now().UTC().Before(*t.ExpiresAt))
+}
+
+const accessTokenColumns = `id, user_id, name, scopes, created_at, expires_at, last_used_at, revoked_at`
+
+func scanAccessToken(row interface{ Scan(...any) error }) (AccessToken, error) {
+	var t AccessToken
+	var scopes string
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &scopes, (*db.Time)(&t.CreatedAt),
+		db.OptionalTime(&t.ExpiresAt), db.OptionalTime(&t.LastUsedAt), db.OptionalTime(&t.RevokedAt))
+	if err != nil {
+		return t, err
+	}
+	t.Scopes = strings.Split(scopes, ",")
+	return t, nil
+}
+
+func (r *sqlUserRepository) CreateAccessToken(userID int, name, tokenHash string, scopes []string, expiresAt *time.Time) (AccessToken, error) {
+	now := time.Now().UTC()
+	t := AccessToken{UserID: userID, Name: name, Scopes: scopes, CreatedAt: now, ExpiresAt: expiresAt}
+
+	var expires interface{}
+	if expiresAt != nil {
+		expires = db.Time(*expiresAt)
+	}
+	query := `INSERT INTO access_tokens (user_id, name, token_hash, scopes, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?) RETURNING id`
+	err := r.db.QueryRow(query, userID, name, tokenHash, strings.Join(scopes, ","), db.Time(now), expires).Scan(&t.ID)
+	if err != nil {
+		return t, fmt.Errorf("failed to create access token: %w", err)
+	}
+	return t, nil
+}
+
+func (r *sqlUserRepository) GetAccessTokenByHash(tokenHash string) (AccessToken, error) {
+	row := r.read.QueryRow(`SELECT `+accessTokenColumns+` FROM access_tokens WHERE token_hash = ?`, tokenHash)
+	t, err := scanAccessToken(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, ErrAccessTokenNotFound
+	}
+	if err != nil {
+		return t, fmt.Errorf("failed to get access token: %w", err)
+	}
+	return t, nil
+}
+
+// GetAccessTokens lists the user's tokens that have not been revoked.
+func (r *sqlUserRepository) GetAccessTokens(userID int) ([]AccessToken, error) {
+	query := `SELECT ` + accessTokenColumns + ` FROM access_tokens WHERE user_id = ? AND revoked_at IS NULL ORDER BY created_at DESC`
+	rows, err := r.read.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query access tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []AccessToken
+	for rows.Next() {
+		t, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan access token: %w", err)
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+func (r *sqlUserRepository) RevokeAccessToken(id, userID int) error {
+	query := `UPDATE access_tokens SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`
+	result, err := r.db.Exec(query, db.Time(time.Now()), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// RevokeAllAccessTokens revokes every personal access token of the user.
+func (r *sqlUserRepository) RevokeAllAccessTokens(userID int) error {
+	query := `UPDATE access_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`
+	if _, err := r.db.Exec(query, db.Time(time.Now()), userID); err != nil {
+		return fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlUserRepository) TouchAccessToken(id int) error {
+	_, err := r.db.Exec(`UPDATE access_tokens SET last_used_at = ? WHERE id = ?`, db.Time(time.Now()), id)
+	if err != nil {
+		return fmt.Errorf("failed to update access token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/db"
+)
+
+// UserSummary is a user as shown to administrators.
+type UserSummary struct {
+	User
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	CardCount        int        `json:"card_count"`
+	DueCount         int        `json:"due_count"`
+	LastActiveAt     *time.Time `json:"last_active_at"`
+}
+
+// Stats describes the usage of the whole instance.
+type Stats struct {
+	Users          int `json:"users"`
+	ActiveUsers    int `json:"active_users"`
+	VerifiedUsers  int `json:"verified_users"`
+	DisabledUsers  int `json:"disabled_users"`
+	Admins         int `json:"admins"`
+	TwoFactorUsers int `json:"two_factor_users"`
+	Cards          int `json:"cards"`
+	CardsThisWeek  int `json:"cards_created_last_7_days"`
+	CardsDue       int `json:"cards_due"`
+	ActiveSessions int `json:"active_sessions"`
+	AccessTokens   int `json:"active_access_tokens"`
+}
+
+// activeUserPeriod is how recently a user must have used a session to
+// count as active.
+const activeUserPeriod = 30 * 24 * time.Hour
+
+var (
+	ErrInvalidRole = errors.New("role must be user or admin")
+	ErrLastAdmin   = errors.New("cannot remove the last active admin")
+)
+
+const userSummaryQuery = `SELECT ` + userColumns + `, totp_enabled_at IS NOT NULL,
+	(SELECT COUNT(*) FROM flashcards f WHERE f.user_id = users.id AND f.deleted_at IS NULL),
+	(SELECT COUNT(*) FROM flashcards f WHERE f.user_id = users.id AND f.deleted_at IS NULL AND NOT f.suspended AND f.next_review < ?),
+	(SELECT MAX(s.last_used_at) FROM sessions s WHERE s.user_id = users.id)
+	FROM users`
+
+func scanUserSummary(row interface{ Scan(...any) error }) (UserSummary, error) {
+	var s UserSummary
+	err := row.Scan(&s.ID, &s.Email, &s.PasswordHash, &s.EmailVerified, &s.Role, &s.Disabled,
+		&s.TwoFactorEnabled, &s.CardCount, &s.DueCount, db.OptionalTime(&s.LastActiveAt))
+	return s, err
+}
+
+// ListUsers returns a page of users whose email contains search, and the
+// total number of matching users.
+func (r *sqlUserRepository) ListUsers(search string, limit, offset int) ([]UserSummary, int, error) {
+	pattern := "%" + strings.ToLower(strings.TrimSpace(search)) + "%"
+
+	var total int
+	if err := r.read.QueryRow(`SELECT COUNT(*) FROM users WHERE LOWER(email) LIKE ?`, pattern).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	query := userSummaryQuery + ` WHERE LOWER(email) LIKE ? ORDER BY id LIMIT ? OFFSET ?`
+	rows, err := r.read.Query(query, db.Time(dueBefore()), pattern, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []UserSummary{}
+	for rows.Next() {
+		s, err := scanUserSummary(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, s)
+	}
+	return users, total, rows.Err()
+}
+
+func (r *sqlUserRepository) GetUserSummary(userID int) (UserSummary, error) {
+	s, err := scanUserSummary(r.read.QueryRow(userSummaryQuery+` WHERE id = ?`, db.Time(dueBefore()), userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return s, ErrUserNotFound
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to get user: %w", err)
+	}
+	return s, nil
+}
+
+func (r *sqlUserRepository) GetStats() (Stats, error) {
+	var s Stats
+	now := time.Now().UTC()
+	counts := []struct {
+		dest  *int
+		query string
+		args  []interface{}
+	}{
+		{&s.Users, `SELECT COUNT(*) FROM users`, nil},
+		{&s.ActiveUsers, `SELECT COUNT(DISTINCT user_id) FROM sessions WHERE last_used_at >= ?`, []interface{}{db.Time(now.Add(-activeUserPeriod))}},
+		{&s.VerifiedUsers, `SELECT COUNT(*) FROM users WHERE email_verified_at IS NOT NULL`, nil},
+		{&s.DisabledUsers, `SELECT COUNT(*) FROM users WHERE disabled_at IS NOT NULL`, nil},
+		{&s.Admins, `SELECT COUNT(*) FROM users WHERE role = ?`, []interface{}{RoleAdmin}},
+		{&s.TwoFactorUsers, `SELECT COUNT(*) FROM users WHERE totp_enabled_at IS NOT NULL`, nil},
+		{&s.Cards, `SELECT COUNT(*) FROM flashcards WHERE deleted_at IS NULL`, nil},
+		{&s.CardsThisWeek, `SELECT COUNT(*) FROM flashcards WHERE deleted_at IS NULL AND created_at >= ?`, []interface{}{db.Time(now.AddDate(0, 0, -7))}},
+		{&s.CardsDue, `SELECT COUNT(*) FROM flashcards WHERE deleted_at IS NULL AND NOT suspended AND next_review < ?`, []interface{}{db.Time(dueBefore())}},
+		{&s.ActiveSessions, `SELECT COUNT(*) FROM sessions WHERE revoked_at IS NULL AND expires_at > ?`, []interface{}{db.Time(now)}},
+		{&s.AccessTokens, `SELECT COUNT(*) FROM access_tokens WHERE revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)`, []interface{}{db.Time(now)}},
+	}
+	for _, c := range counts {
+		if err := r.read.QueryRow(c.query, c.args...).Scan(c.dest); err != nil {
+			return s, fmt.Errorf("failed to compute stats: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// keepsAnAdmin is the condition under which the users row being updated
+// may lose the admin role or be disabled: it is not an active admin, or
+// another active admin remains. It takes RoleAdmin twice as arguments.
+const keepsAnAdmin = `(role <> ? OR disabled_at IS NOT NULL OR
+	(SELECT COUNT(*) FROM users WHERE role = ? AND disabled_at IS NULL) > 1)`
+
+// lockAdmins locks the rows of the active admins until tx ends, so that
+// concurrent demotions on PostgreSQL wait for each other before counting
+// the admins left. SQLite already runs one write transaction at a time.
+func lockAdmins(tx DBTX) error {
+	if db.Dialect != db.Postgres {
+		return nil
+	}
+	rows, err := tx.Query(`SELECT id FROM users WHERE role = ? AND disabled_at IS NULL FOR UPDATE`, RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("failed to lock admins: %w", err)
+	}
+	return rows.Close()
+}
+
+func (r *sqlUserRepository) SetRole(userID int, role string) error {
+	if role != RoleUser && role != RoleAdmin {
+		return ErrInvalidRole
+	}
+	return inTx(r.db, func(tx DBTX) error {
+		query := `UPDATE users SET role = ? WHERE id = ?`
+		args := []interface{}{role, userID}
+		if role != RoleAdmin {
+			if err := lockAdmins(tx); err != nil {
+				return err
+			}
+			query += ` AND ` + keepsAnAdmin
+			args = append(args, RoleAdmin, RoleAdmin)
+		}
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to set role: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			if _, err := getUser(tx, "id = ?", userID); err != nil {
+				return err
+			}
+			return ErrLastAdmin
+		}
+		return nil
+	})
+}
+
+// DisableUser blocks the user from signing in and revokes all their
+// sessions and personal access tokens.
+func (r *sqlUserRepository) DisableUser(userID int) error {
+	return inTx(r.db, func(tx DBTX) error {
+		if err := lockAdmins(tx); err != nil {
+			return err
+		}
+		user, err := getUser(tx, "id = ?", userID)
+		if err != nil {
+			return err
+		}
+
+		now := db.Time(time.Now())
+		result, err := tx.Exec(`UPDATE users SET disabled_at = ? WHERE id = ? AND disabled_at IS NULL AND `+keepsAnAdmin,
+			now, userID, RoleAdmin, RoleAdmin)
+		if err != nil {
+			return fmt.Errorf("failed to disable user: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 && !user.Disabled {
+			return ErrLastAdmin
+		}
+
+		queries := []string{
+			`UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`,
+			`UPDATE access_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`,
+		}
+		for _, query := range queries {
+			if _, err := tx.Exec(query, now, userID); err != nil {
+				return fmt.Errorf("failed to disable user: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *sqlUserRepository) EnableUser(userID int) error {
+	result, err := r.db.Exec(`UPDATE users SET disabled_at = NULL WHERE id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to enable user: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// PromoteAdmins gives the admin role to the users with the given emails.
+func (r *sqlUserRepository) PromoteAdmins(emails []string) error {
+	for _, email := range emails {
+		email = strings.TrimSpace(email)
+		if email == "" {
+			continue
+		}
+		if _, err := r.db.Exec(`UPDATE users SET role = ? WHERE LOWER(email) = LOWER(?)`, RoleAdmin, email); err != nil {
+			return fmt.Errorf("failed to promote admin: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package models_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Danyarbrg/flashCards/internal/db"
+	"github.com/Danyarbrg/flashCards/internal/db/dbtest"
+	"github.com/Danyarbrg/flashCards/internal/models"
+)
+
+func TestSQLiteLastAdmin(t *testing.T) {
+	testLastAdmin(t)
+}
+
+func TestPostgresLastAdmin(t *testing.T) {
+	dbtest.UsePostgres(t)
+	testLastAdmin(t)
+}
+
+// testLastAdmin demotes and disables two admins at the same time, which
+// must leave one of them an active admin.
+func testLastAdmin(t *testing.T) {
+	users := models.NewSQLUserRepository(db.DB, db.Read)
+	for i := 0; i < 20; i++ {
+		a, b := newUser(t, users), newUser(t, users)
+		for _, user := range []models.User{a, b} {
+			if err := users.SetRole(user.ID, models.RoleAdmin); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs[0] = users.SetRole(a.ID, models.RoleUser)
+		}()
+		go func() {
+			defer wg.Done()
+			errs[1] = users.DisableUser(b.ID)
+		}()
+		wg.Wait()
+
+		if (errs[0] == nil) == (errs[1] == nil) {
+			t.Fatalf("demote and disable: errors %v and %v, want exactly one ErrLastAdmin", errs[0], errs[1])
+		}
+		last := a
+		if errs[0] == nil {
+			last = b
+		}
+		for _, err := range errs {
+			if err != nil && !errors.Is(err, models.ErrLastAdmin) {
+				t.Fatalf("err = %v, want ErrLastAdmin", err)
+			}
+		}
+
+		if err := users.SetRole(last.ID, models.RoleUser); !errors.Is(err, models.ErrLastAdmin) {
+			t.Errorf("demoting the last admin: err = %v", err)
+		}
+		if err := users.DisableUser(last.ID); !errors.Is(err, models.ErrLastAdmin) {
+			t.Errorf("disabling the last admin: err = %v", err)
+		}
+		// Clean up this round's admins so the next round starts from none.
+		if _, err := db.DB.Exec(`UPDATE users SET role = ?, disabled_at = NULL WHERE id IN (?, ?)`, models.RoleUser, a.ID, b.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	user := newUser(t, users)
+	if err := users.SetRole(user.ID, models.RoleUser); err != nil {
+		t.Errorf("demoting a user who is not an admin: %v", err)
+	}
+	if err := users.DisableUser(user.ID); err != nil {
+		t.Errorf("disabling a user who is not an admin: %v", err)
+	}
+	if err := users.DisableUser(user.ID); err != nil {
+		t.Errorf("disabling a disabled user again: %v", err)
+	}
+	if err := users.SetRole(1<<30, models.RoleUser); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("missing user: err = %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/lemma"
+)
+
+// MaxBulkOperations caps the operations of one bulk request, including
+// those a query expands to.
+const MaxBulkOperations = 1000
+
+// ErrBulkFailed is returned by RunBulk when an operation failed and the
+// whole batch was rolled back.
+var ErrBulkFailed = errors.New("bulk operation failed")
+
+// ErrBulkTooLarge is returned when a request has, or a query matches, more
+// than MaxBulkOperations operations.
+var ErrBulkTooLarge = fmt.Errorf("at most %d operations are allowed", MaxBulkOperations)
+
+// CardQuery selects a user's cards. Empty fields match every card.
+type CardQuery struct {
+	Tag       string `json:"tag"`
+	Search    string `json:"search"` // matched against word and meaning
+	Due       bool   `json:"due"`
+	Suspended *bool  `json:"suspended"`
+}
+
+// filterTag keeps the cards that have q.Tag as one of their tags.
+func (q CardQuery) filterTag(cards []Flashcard) []Flashcard {
+	if q.Tag == "" {
+		return cards
+	}
+	var matched []Flashcard
+	for _, f := range cards {
+		if hasTag(f.Tags, q.Tag) {
+			matched = append(matched, f)
+		}
+	}
+	return matched
+}
+
+func hasTag(tags, tag string) bool {
+	for _, t := range strings.Split(tags, ",") {
+		if strings.EqualFold(strings.TrimSpace(t), strings.TrimSpace(tag)) {
+			return true
+		}
+	}
+	return false
+}
+
+// BulkOperation is one change to a card. Op is one of create, update,
+// delete, tag, suspend, unsuspend or reschedule. update only changes the
+// fields that are set. create refuses duplicates like POST /cards does,
+// unless Force is set.
+type BulkOperation struct {
+	Op         string     `json:"op"`
+	ID         int        `json:"id,omitempty"`
+	Word       *string    `json:"word,omitempty"`
+	Meaning    *string    `json:"meaning,omitempty"`
+	Example    *string    `json:"example,omitempty"`
+	Tags       *string    `json:"tags,omitempty"`
+	AddTags    []string   `json:"add_tags,omitempty"`
+	RemoveTags []string   `json:"remove_tags,omitempty"`
+	NextReview *time.Time `json:"next_review,omitempty"`
+	Force      bool       `json:"force,omitempty"`
+	// Version, if set, must match the card. create has no version.
+	Version int `json:"version,omitempty"`
+}
+
+// bulkOps are the operations RunBulk knows.
+var bulkOps = map[string]bool{
+	"create": true, "update": true, "delete": true, "tag": true,
+	"suspend": true, "unsuspend": true, "reschedule": true,
+}
+
+// validate checks that the operation is one RunBulk knows.
+func (op BulkOperation) validate() error {
+	switch {
+	case op.Op == "move":
+		return fmt.Errorf("move is not supported: cards are not organised in decks")
+	case !bulkOps[op.Op]:
+		return fmt.Errorf("unknown op %q", op.Op)
+	case op.Op == "create" && op.Version != 0:
+		return fmt.Errorf("create cannot have a version")
+	case op.Op != "create" && op.Force:
+		return fmt.Errorf("only create can be forced")
+	}
+	return nil
+}
+
+// BulkRequest is either a list of operations, or a query and an action
+// that is applied to every card the query matches.
+type BulkRequest struct {
+	Operations []BulkOperation `json:"operations"`
+	Query      *CardQuery      `json:"query"`
+	Action     *BulkOperation  `json:"action"`
+}
+
+// Validate checks the shape of the request and that every operation is
+// known; whether the operations apply is checked when they run.
+func (req BulkRequest) Validate() error {
+	for i, op := range req.Operations {
+		if err := op.validate(); err != nil {
+			return fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	if req.Action != nil {
+		if err := req.Action.validate(); err != nil {
+			return fmt.Errorf("action: %w", err)
+		}
+	}
+
+	switch {
+	case req.Query != nil && len(req.Operations) > 0:
+		return fmt.Errorf("send either operations or a query and an action, not both")
+	case req.Query != nil:
+		if req.Action == nil {
+			return fmt.Errorf("a query needs an action")
+		}
+		if req.Action.Op == "create" {
+			return fmt.Errorf("create cannot be applied to a query")
+		}
+		if req.Action.ID != 0 || req.Action.Version != 0 {
+			return fmt.Errorf("the action of a query must not have an id or version")
+		}
+	case req.Action != nil:
+		return fmt.Errorf("an action needs a query")
+	case len(req.Operations) == 0:
+		return fmt.Errorf("no operations")
+	case len(req.Operations) > MaxBulkOperations:
+		return ErrBulkTooLarge
+	}
+	return nil
+}
+
+// BulkResult is the outcome of one operation. Status is ok, error,
+// rolled_back for operations that succeeded but were undone, or skipped
+// for operations after the failed one.
+type BulkResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	ID     int    `json:"id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// RunBulk applies req to the user's cards in one transaction. If an
+// operation fails, it stops, nothing is saved and ErrBulkFailed is
+// returned along with the results.
+func RunBulk(cards CardRepository, userID int, req BulkRequest) ([]BulkResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	var results []BulkResult
+	failed := false
+	err := cards.Transaction(func(tx CardRepository) error {
+		ops := req.Operations
+		if req.Query != nil {
+			matched, err := tx.Search(userID, *req.Query)
+			if err != nil {
+				return err
+			}
+			if len(matched) > MaxBulkOperations {
+				return fmt.Errorf("the query matches %d cards: %w", len(matched), ErrBulkTooLarge)
+			}
+			ops = make([]BulkOperation, len(matched))
+			for i, f := range matched {
+				ops[i] = *req.Action
+				ops[i].ID = f.ID
+			}
+		}
+
+		results = make([]BulkResult, len(ops))
+		for i, op := range ops {
+			results[i] = BulkResult{Index: i, Op: op.Op, ID: op.ID, Status: "skipped"}
+		}
+		for i, op := range ops {
+			id, err := applyBulk(tx, userID, op)
+			results[i].ID = id
+			if err != nil {
+				results[i].Status, results[i].Error = "error", err.Error()
+				for j := range results[:i] {
+					results[j].Status = "rolled_back"
+				}
+				failed = true
+				return ErrBulkFailed
+			}
+			results[i].Status = "ok"
+		}
+		return nil
+	})
+	if failed {
+		return results, ErrBulkFailed
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// applyBulk runs one operation and returns the ID of the card it changed.
+func applyBulk(cards CardRepository, userID int, op BulkOperation) (int, error) {
+	if op.Op == "create" {
+		card := Flashcard{UserID: userID, Word: deref(op.Word), Meaning: deref(op.Meaning),
+			Example: deref(op.Example), Tags: deref(op.Tags)}
+		if err := checkDuplicates(cards, card, op.Force); err != nil {
+			return 0, err
+		}
+		if err := cards.Create(&card); err != nil {
+			return 0, err
+		}
+		return card.ID, nil
+	}
+	if op.ID == 0 {
+		return 0, fmt.Errorf("id is required")
+	}
+
+	switch op.Op {
+	case "update", "tag":
+		card, err := cards.GetByID(op.ID, userID)
+		if err != nil {
+			return op.ID, err
+		}
+		word, meaning, example, tags := card.Word, card.Meaning, card.Example, card.Tags
+		if op.Op == "update" {
+			word, meaning, example, tags = orDefault(op.Word, word), orDefault(op.Meaning, meaning),
+				orDefault(op.Example, example), orDefault(op.Tags, tags)
+		} else {
+			if len(op.AddTags) == 0 && len(op.RemoveTags) == 0 {
+				return op.ID, fmt.Errorf("add_tags or remove_tags is required")
+			}
+			tags = editTags(tags, op.AddTags, op.RemoveTags)
+		}
+		if word == "" || meaning == "" {
+			return op.ID, fmt.Errorf("word and meaning must not be empty")
+		}
+		_, err = cards.Update(op.ID, userID, op.Version, word, meaning, example, tags)
+		return op.ID, err
+	case "delete":
+		// Delete ignores missing cards; a bulk delete should report them.
+		if _, err := cards.GetByID(op.ID, userID); err != nil {
+			return op.ID, err
+		}
+		return op.ID, cards.Delete(op.ID, userID, op.Version)
+	case "suspend", "unsuspend":
+		return op.ID, cards.SetSuspended(op.ID, userID, op.Version, op.Op == "suspend")
+	case "reschedule":
+		if op.NextReview == nil {
+			return op.ID, fmt.Errorf("next_review is required")
+		}
+		return op.ID, cards.Reschedule(op.ID, userID, op.Version, *op.NextReview)
+	default:
+		return op.ID, fmt.Errorf("unknown op %q", op.Op)
+	}
+}
+
+// checkDuplicates applies the duplicate rules of POST /cards to a card
+// about to be created. Cards created earlier in the batch count too.
+func checkDuplicates(cards CardRepository, card Flashcard, force bool) error {
+	duplicates, err := cards.FindDuplicates(card.UserID, card.Word, lemma.Default())
+	if err != nil {
+		return err
+	}
+	blocking, exact := BlockingDuplicates(duplicates, card.Meaning, force)
+	if exact {
+		return fmt.Errorf("card %d has the same word and meaning", blocking[0].ID)
+	}
+	if len(blocking) > 0 {
+		ids := make([]string, len(blocking))
+		for i, d := range blocking {
+			ids[i] = strconv.Itoa(d.ID)
+		}
+		return fmt.Errorf("possible duplicate of card %s, set force to create anyway", strings.Join(ids, ", "))
+	}
+	return nil
+}
+
+// editTags adds and removes tags from a comma-separated list, keeping the
+// order of the tags that stay.
+func editTags(tags string, add, remove []string) string {
+	var kept []string
+	for _, t := range strings.Split(tags, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" || hasTag(strings.Join(remove, ","), t) || hasTag(strings.Join(kept, ","), t) {
+			continue
+		}
+		kept = append(kept, t)
+	}
+	for _, t := range add {
+		t = strings.TrimSpace(t)
+		if t != "" && !hasTag(strings.Join(kept, ","), t) {
+			kept = append(kept, t)
+		}
+	}
+	return strings.Join(kept, ",")
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func orDefault(s *string, def string) string {
+	if s == nil {
+		return def
+	}
+	return *s
+}
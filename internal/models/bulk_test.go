@@ -0,0 +1,120 @@
+package models_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/models"
+)
+
+func TestBulkRequestValidate(t *testing.T) {
+	word := "word"
+	query := &models.CardQuery{Tag: "tag"}
+	tests := []struct {
+		name string
+		req  models.BulkRequest
+		want string
+	}{
+		{"move", models.BulkRequest{Operations: []models.BulkOperation{{Op: "move", ID: 1}}}, "move is not supported"},
+		{"unknown op", models.BulkRequest{Operations: []models.BulkOperation{{Op: "create", Word: &word}, {Op: "copy", ID: 1}}}, `operation 1: unknown op "copy"`},
+		{"move action", models.BulkRequest{Query: query, Action: &models.BulkOperation{Op: "move"}}, "move is not supported"},
+		{"create with version", models.BulkRequest{Operations: []models.BulkOperation{{Op: "create", Version: 1}}}, "version"},
+		{"action with version", models.BulkRequest{Query: query, Action: &models.BulkOperation{Op: "suspend", Version: 2}}, "version"},
+		{"forced update", models.BulkRequest{Operations: []models.BulkOperation{{Op: "update", ID: 1, Force: true}}}, "only create"},
+		{"no operations", models.BulkRequest{}, "no operations"},
+	}
+	for _, tt := range tests {
+		err := tt.req.Validate()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	ok := models.BulkRequest{Operations: []models.BulkOperation{{Op: "suspend", ID: 1, Version: 3}}}
+	if err := ok.Validate(); err != nil {
+		t.Errorf("suspend with a version: %v", err)
+	}
+}
+
+// A stale version fails the operation and rolls back the whole request,
+// whichever op it is given to.
+func TestRunBulkChecksVersions(t *testing.T) {
+	cards, users := models.NewMemoryCardRepository(), models.NewMemoryUserRepository()
+	user := newUser(t, users)
+	first := newCard(t, cards, user.ID, "first", "")
+	second := newCard(t, cards, user.ID, "second", "")
+	next := time.Now().AddDate(0, 0, 5)
+
+	for _, op := range []models.BulkOperation{
+		{Op: "suspend", ID: second.ID, Version: second.Version + 1},
+		{Op: "unsuspend", ID: second.ID, Version: second.Version + 1},
+		{Op: "reschedule", ID: second.ID, NextReview: &next, Version: second.Version + 1},
+		{Op: "delete", ID: second.ID, Version: second.Version + 1},
+	} {
+		req := models.BulkRequest{Operations: []models.BulkOperation{
+			{Op: "suspend", ID: first.ID, Version: first.Version},
+			op,
+		}}
+		results, err := models.RunBulk(cards, user.ID, req)
+		if !errors.Is(err, models.ErrBulkFailed) {
+			t.Fatalf("%s: err = %v", op.Op, err)
+		}
+		if results[0].Status != "rolled_back" || results[1].Status != "error" ||
+			!strings.Contains(results[1].Error, models.ErrVersionConflict.Error()) {
+			t.Errorf("%s: results = %+v", op.Op, results)
+		}
+		if got := getCard(t, cards, first.ID, user.ID); got.Suspended || got.Version != first.Version {
+			t.Errorf("%s: first card after rollback = %+v", op.Op, got)
+		}
+	}
+
+	req := models.BulkRequest{Operations: []models.BulkOperation{
+		{Op: "suspend", ID: first.ID, Version: first.Version},
+		{Op: "reschedule", ID: second.ID, NextReview: &next, Version: second.Version},
+	}}
+	if _, err := models.RunBulk(cards, user.ID, req); err != nil {
+		t.Fatal(err)
+	}
+	if got := getCard(t, cards, first.ID, user.ID); !got.Suspended {
+		t.Errorf("first card = %+v", got)
+	}
+}
+
+// create refuses duplicates like POST /cards, including those of cards
+// created earlier in the same request.
+func TestRunBulkChecksDuplicates(t *testing.T) {
+	cards, users := models.NewMemoryCardRepository(), models.NewMemoryUserRepository()
+	user := newUser(t, users)
+	existing := newCard(t, cards, user.ID, "bank", "")
+	create := func(word, meaning string, force bool) models.BulkOperation {
+		return models.BulkOperation{Op: "create", Word: &word, Meaning: &meaning, Force: force}
+	}
+
+	tests := []struct {
+		name string
+		ops  []models.BulkOperation
+		want string
+	}{
+		{"same word and meaning", []models.BulkOperation{create("Bank", existing.Meaning, false)}, "same word and meaning"},
+		{"same word and meaning, forced", []models.BulkOperation{create("bank", existing.Meaning, true)}, "same word and meaning"},
+		{"same word", []models.BulkOperation{create("bank", "a river side", false)}, "possible duplicate"},
+		{"within the request", []models.BulkOperation{create("tree", "a plant", false), create("trees", "plants", false)}, "possible duplicate"},
+		{"homonym, forced", []models.BulkOperation{create("bank", "a river side", true)}, ""},
+		{"new word", []models.BulkOperation{create("river", "flowing water", false)}, ""},
+	}
+	for _, tt := range tests {
+		results, err := models.RunBulk(cards, user.ID, models.BulkRequest{Operations: tt.ops})
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		last := results[len(results)-1]
+		if !errors.Is(err, models.ErrBulkFailed) || !strings.Contains(last.Error, tt.want) {
+			t.Errorf("%s: err = %v, results = %+v, want %q", tt.name, err, results, tt.want)
+		}
+	}
+}
@@ -1,15 +1,17 @@
 package models
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
 	"github.com/Danyarbrg/flashCards/internal/db"
+	"github.com/Danyarbrg/flashCards/internal/lemma"
 )
 
-const timeFormat = "2006-01-02T15:04:05Z"
 
 type Flashcard struct {
 	ID          int       `json:"id"`
@@ -23,53 +25,158 @@ type Flashcard struct {
 	Repetitions int       `json:"repetitions"`
 	EF          float64   `json:"ef"`
 	CreatedAt   time.Time `json:"created_at"`
+	// Version goes up with every change of the card.
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Suspended cards are never due.
+	Suspended bool `json:"suspended"`
+	// DeletedAt is set while the card is in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
-func (f *Flashcard) Save() error {
+var ErrCardNotFound = errors.New("flashcard not found")
+
+// ErrVersionConflict is returned when a card was changed since the version
+// the caller based its change on.
+var ErrVersionConflict = errors.New("flashcard was changed by someone else")
+
+func (f *Flashcard) validate() error {
 	if f.Word == "" || f.Meaning == "" {
 		return fmt.Errorf("word and meaning are required")
 	}
 	if f.UserID == 0 {
 		return fmt.Errorf("user_id is required")
 	}
+	return nil
+}
 
-	now := time.Now().UTC()
-	query := `
-	INSERT INTO flashcards (user_id, word, meaning, example, tags, next_review, interval, repetitions, ef, created_at) 
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+// ApplyReview updates the schedule of the card with the SM-2 algorithm for
+// an answer of the given quality (0-5).
+func (f *Flashcard) ApplyReview(quality int, now time.Time) {
+	if quality < 3 {
+		f.Repetitions = 0
+		f.Interval = 1
+	} else {
+		if f.Repetitions == 0 {
+			f.Interval = 1
+		} else if f.Repetitions == 1 {
+			f.Interval = 6
+		} else {
+			f.Interval = int(float64(f.Interval) * f.EF)
+		}
+		f.Repetitions++
+		f.EF += (0.1 - float64(5-quality)*(0.08+float64(5-quality)*0.02))
+		if f.EF < 1.3 {
+			f.EF = 1.3
+		}
+	}
 
-	result, err := db.DB.Exec(query, f.UserID, f.Word, f.Meaning, f.Example, f.Tags, now.Format(timeFormat), 1, 0, 2.5, now.Format(timeFormat))
-	if err != nil {
-		return fmt.Errorf("failed to save flashcard: %w", err)
+	f.NextReview = now.UTC().Truncate(24*time.Hour).AddDate(0, 0, f.Interval)
+}
+
+// dueBefore returns the end of the current day; cards scheduled before it
+// are due.
+func dueBefore() time.Time {
+	return time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+}
+
+type sqlCardRepository struct {
+	db   DBTX
+	read DBTX
+}
+
+// NewSQLCardRepository returns a CardRepository that writes to db and runs
+// plain queries on read. Pass the same handle twice for a transaction.
+func NewSQLCardRepository(db, read DBTX) CardRepository {
+	return &sqlCardRepository{db: db, read: read}
+}
+
+func (r *sqlCardRepository) Create(f *Flashcard) error {
+	if err := f.validate(); err != nil {
+		return err
 	}
 
-	lastID, err := result.LastInsertId()
+	now := time.Now().UTC()
+	query := `
+	INSERT INTO flashcards (user_id, word, meaning, example, tags, next_review, interval, repetitions, ef, created_at, version, updated_at) 
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`
+
+	err := inTx(r.db, func(tx DBTX) error {
+		err := tx.QueryRow(query, f.UserID, f.Word, f.Meaning, f.Example, f.Tags, db.Time(now), 1, 0, 2.5, db.Time(now), 1, db.Time(now)).Scan(&f.ID)
+		if err != nil {
+			return fmt.Errorf("failed to save flashcard: %w", err)
+		}
+		return addRevision(tx, *f, f.UserID, now)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get last insert ID: %w", err)
+		return err
 	}
 
-	f.ID = int(lastID)
 	f.NextReview = now
 	f.CreatedAt = now
+	f.Interval = 1
+	f.Repetitions = 0
+	f.EF = 2.5
+	f.Version = 1
+	f.UpdatedAt = now
 	return nil
 }
 
-func Update(id, userID int, word, meaning, example, tags string) error {
-	query := `UPDATE flashcards SET word = ?, meaning = ?, example = ?, tags = ? WHERE id = ? AND user_id = ?`
-	_, err := db.DB.Exec(query, word, meaning, example, tags, id, userID)
+// Update changes the card's fields, records the result as a revision and
+// returns the card as it now is. Unless version is 0, the card must still be
+// at that version.
+func (r *sqlCardRepository) Update(id, userID, version int, word, meaning, example, tags string) (Flashcard, error) {
+	var updated Flashcard
+	err := inTx(r.db, func(tx DBTX) error {
+		card, err := getCard(tx, id, userID)
+		if err != nil {
+			return err
+		}
+		if version != 0 && card.Version != version {
+			return ErrVersionConflict
+		}
+		updated, err = updateCard(tx, card, userID, word, meaning, example, tags)
+		return err
+	})
+	return updated, err
+}
+
+const cardColumns = `id, user_id, word, meaning, example, tags, next_review, interval, repetitions, ef, created_at, version, updated_at, suspended, deleted_at`
+
+func scanCard(row interface{ Scan(...any) error }) (Flashcard, error) {
+	var f Flashcard
+	err := row.Scan(&f.ID, &f.UserID, &f.Word, &f.Meaning, &f.Example, &f.Tags, (*db.Time)(&f.NextReview),
+		&f.Interval, &f.Repetitions, &f.EF, (*db.Time)(&f.CreatedAt), &f.Version, (*db.Time)(&f.UpdatedAt), &f.Suspended, db.OptionalTime(&f.DeletedAt))
+	return f, err
+}
+
+func (r *sqlCardRepository) queryCards(query string, args ...interface{}) ([]Flashcard, error) {
+	rows, err := r.read.Query(query, args...)
 	if err != nil {
-		return fmt.Errorf("failed to update flashcard: %w", err)
+		return nil, fmt.Errorf("failed to query flashcards: %w", err)
 	}
-	return nil
-}
+	defer rows.Close()
 
-func GetSortedPaginated(userID, limit, offset int, sortBy, order, tagFilter string) ([]Flashcard, error) {
-	validSortFields := map[string]string{
-		"created":     "created_at",
-		"repetitions": "repetitions",
-		"ef":          "ef",
-		"next_review": "next_review",
+	var cards []Flashcard
+	for rows.Next() {
+		f, err := scanCard(rows)
+		if err != nil {
+			log.Printf("Error scanning flashcard: %v", err)
+			return nil, fmt.Errorf("failed to scan flashcard: %w", err)
+		}
+		cards = append(cards, f)
 	}
+	return cards, rows.Err()
+}
+
+var validSortFields = map[string]string{
+	"created":     "created_at",
+	"repetitions": "repetitions",
+	"ef":          "ef",
+	"next_review": "next_review",
+}
+
+func (r *sqlCardRepository) List(userID, limit, offset int, sortBy, order, tagFilter string) ([]Flashcard, error) {
 	orderBy, ok := validSortFields[sortBy]
 	if !ok {
 		orderBy = "created_at"
@@ -79,7 +186,7 @@ func GetSortedPaginated(userID, limit, offset int, sortBy, order, tagFilter stri
 		orderDir = "DESC"
 	}
 
-	baseQuery := `SELECT id, user_id, word, meaning, example, tags, next_review, interval, repetitions, ef, created_at FROM flashcards WHERE user_id = ?`
+	baseQuery := `SELECT ` + cardColumns + ` FROM flashcards WHERE user_id = ? AND deleted_at IS NULL`
 	args := []interface{}{userID}
 
 	if tagFilter != "" {
@@ -90,147 +197,344 @@ func GetSortedPaginated(userID, limit, offset int, sortBy, order, tagFilter stri
 	fullQuery := fmt.Sprintf("%s ORDER BY %s %s LIMIT ? OFFSET ?", baseQuery, orderBy, orderDir)
 	args = append(args, limit, offset)
 
-	rows, err := db.DB.Query(fullQuery, args...)
+	return r.queryCards(fullQuery, args...)
+}
+
+func (r *sqlCardRepository) GetByID(id, userID int) (Flashcard, error) {
+	return getCard(r.read, id, userID)
+}
+
+func getCard(q DBTX, id, userID int) (Flashcard, error) {
+	query := `SELECT ` + cardColumns + ` FROM flashcards WHERE id = ? AND user_id = ? AND deleted_at IS NULL`
+	card, err := scanCard(q.QueryRow(query, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return card, ErrCardNotFound
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to query sorted flashcards: %w", err)
+		return card, fmt.Errorf("failed to get flashcard: %w", err)
 	}
-	defer rows.Close()
+	return card, nil
+}
 
-	var cards []Flashcard
-	for rows.Next() {
-		var f Flashcard
-		var nextReviewStr, createdAtStr string
-		if err := rows.Scan(&f.ID, &f.UserID, &f.Word, &f.Meaning, &f.Example, &f.Tags, &nextReviewStr, &f.Interval, &f.Repetitions, &f.EF, &createdAtStr); err != nil {
-			log.Printf("Error scanning flashcard: %v", err)
-			return nil, fmt.Errorf("failed to scan flashcard: %w", err)
+func (r *sqlCardRepository) GetDue(userID int) ([]Flashcard, error) {
+	query := `SELECT ` + cardColumns + ` FROM flashcards WHERE user_id = ? AND deleted_at IS NULL AND NOT suspended AND next_review < ?`
+	return r.queryCards(query, userID, db.Time(dueBefore()))
+}
+
+// Review reads and reschedules the card in one transaction. The update only
+// applies to the version that was read, so of two concurrent reviews of the
+// same card one fails with ErrVersionConflict instead of being lost; on
+// PostgreSQL both can read the card before either writes.
+func (r *sqlCardRepository) Review(id, userID, quality int) error {
+	return inTx(r.db, func(tx DBTX) error {
+		card, err := getCard(tx, id, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get flashcard: %w", err)
 		}
-		f.NextReview, _ = time.Parse(timeFormat, nextReviewStr)
-		f.CreatedAt, _ = time.Parse(timeFormat, createdAtStr)
-		cards = append(cards, f)
-	}
-	return cards, nil
+
+		card.ApplyReview(quality, time.Now())
+
+		query := `UPDATE flashcards SET repetitions = ?, interval = ?, ef = ?, next_review = ?, ` + bumpVersion + `
+				WHERE id = ? AND user_id = ? AND version = ?`
+		result, err := tx.Exec(query, card.Repetitions, card.Interval, card.EF, db.Time(card.NextReview), db.Time(time.Now()), id, userID, card.Version)
+		if err != nil {
+			return fmt.Errorf("failed to update flashcard: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return ErrVersionConflict
+		}
+		return nil
+	})
+}
+
+// SetSuspended suspends or unsuspends the card. Unless version is 0, the
+// card must still be at that version.
+func (r *sqlCardRepository) SetSuspended(id, userID, version int, suspended bool) error {
+	return r.setCard(id, userID, version, `suspended = ?`, suspended)
+}
+
+// Reschedule sets when the card is next due, keeping its interval. Unless
+// version is 0, the card must still be at that version.
+func (r *sqlCardRepository) Reschedule(id, userID, version int, next time.Time) error {
+	return r.setCard(id, userID, version, `next_review = ?`, db.Time(next))
 }
 
-func GetByID(id, userID int) (Flashcard, error) {
-	query := `SELECT id, user_id, word, meaning, example, tags, next_review, interval, repetitions, ef, created_at 
-			FROM flashcards 
-			WHERE id = ? AND user_id = ?`
-	row := db.DB.QueryRow(query, id, userID)
+// setCard runs an update of one card with the SET clause set and its
+// arguments. Unless version is 0, it only applies to that version and fails
+// with ErrVersionConflict if the card is at another.
+func (r *sqlCardRepository) setCard(id, userID, version int, set string, args ...any) error {
+	return inTx(r.db, func(tx DBTX) error {
+		query := `UPDATE flashcards SET ` + set + `, ` + bumpVersion + ` WHERE id = ? AND user_id = ? AND deleted_at IS NULL`
+		args = append(args, db.Time(time.Now()), id, userID)
+		if version != 0 {
+			query += ` AND version = ?`
+			args = append(args, version)
+		}
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to update flashcard: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			if _, err := getCard(tx, id, userID); err != nil {
+				return err
+			}
+			return ErrVersionConflict
+		}
+		return nil
+	})
+}
 
-	var card Flashcard
-	var nextReviewStr, createdAtStr string
-	err := row.Scan(&card.ID, &card.UserID, &card.Word, &card.Meaning, &card.Example, &card.Tags, &nextReviewStr, &card.Interval, &card.Repetitions, &card.EF, &createdAtStr)
+// bumpVersion marks a card as changed; it takes the time as a parameter.
+const bumpVersion = `version = version + 1, updated_at = ?`
+
+// execCard runs an update of one card, returning ErrCardNotFound if it
+// matched no row.
+func (r *sqlCardRepository) execCard(query string, args ...any) error {
+	result, err := r.db.Exec(query, args...)
 	if err != nil {
-		return card, fmt.Errorf("failed to get flashcard: %w", err)
+		return fmt.Errorf("failed to update flashcard: %w", err)
 	}
-	card.NextReview, _ = time.Parse(timeFormat, nextReviewStr)
-	card.CreatedAt, _ = time.Parse(timeFormat, createdAtStr)
-	return card, nil
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrCardNotFound
+	}
+	return nil
 }
 
-func GetDueFlashcards(userID int) ([]Flashcard, error) {
-    now := time.Now().UTC().Truncate(24 * time.Hour).AddDate(0, 0, 1)
-    query := `SELECT id, user_id, word, meaning, example, tags, next_review, interval, repetitions, ef, created_at 
-            FROM flashcards 
-            WHERE user_id = ? AND next_review < ?`
-    rows, err := db.DB.Query(query, userID, now.Format(timeFormat))
-    if err != nil {
-        return nil, fmt.Errorf("failed to query due flashcards: %w", err)
-    }
-    defer rows.Close()
-
-    var cards []Flashcard
-    for rows.Next() {
-        var f Flashcard
-        var nextReviewStr, createdAtStr string
-        if err := rows.Scan(&f.ID, &f.UserID, &f.Word, &f.Meaning, &f.Example, &f.Tags, &nextReviewStr, &f.Interval, &f.Repetitions, &f.EF, &createdAtStr); err != nil {
-            log.Printf("Error scanning flashcard: %v", err)
-            return nil, fmt.Errorf("failed to scan flashcard: %w", err)
-        }
-        f.NextReview, _ = time.Parse(timeFormat, nextReviewStr)
-        f.CreatedAt, _ = time.Parse(timeFormat, createdAtStr)
-        cards = append(cards, f)
-    }
-    return cards, nil
-}
-
-func UpdateAfterReview(id, userID, quality int) error {
-	card, err := GetByID(id, userID)
+// Search returns the user's cards matching q, ordered by ID.
+func (r *sqlCardRepository) Search(userID int, q CardQuery) ([]Flashcard, error) {
+	query := `SELECT ` + cardColumns + ` FROM flashcards WHERE user_id = ? AND deleted_at IS NULL`
+	args := []interface{}{userID}
+	if q.Tag != "" {
+		query += ` AND LOWER(tags) LIKE ?`
+		args = append(args, "%"+strings.ToLower(q.Tag)+"%")
+	}
+	if q.Search != "" {
+		query += ` AND (LOWER(word) LIKE ? OR LOWER(meaning) LIKE ?)`
+		pattern := "%" + strings.ToLower(q.Search) + "%"
+		args = append(args, pattern, pattern)
+	}
+	if q.Due {
+		query += ` AND NOT suspended AND next_review < ?`
+		args = append(args, db.Time(dueBefore()))
+	}
+	if q.Suspended != nil {
+		query += ` AND suspended = ?`
+		args = append(args, *q.Suspended)
+	}
+
+	cards, err := r.queryCards(query+` ORDER BY id`, args...)
 	if err != nil {
-		return fmt.Errorf("failed to get flashcard: %w", err)
+		return nil, err
 	}
+	// LIKE also matches tags that merely contain q.Tag.
+	return q.filterTag(cards), nil
+}
 
-	if quality < 3 {
-		card.Repetitions = 0
-		card.Interval = 1
-	} else {
-		if card.Repetitions == 0 {
-			card.Interval = 1
-		} else if card.Repetitions == 1 {
-			card.Interval = 6
-		} else {
-			card.Interval = int(float64(card.Interval) * card.EF)
+// Transaction runs fn with a repository whose changes are committed
+// together, or not at all if fn returns an error.
+func (r *sqlCardRepository) Transaction(fn func(CardRepository) error) error {
+	return inTx(r.db, func(tx DBTX) error {
+		return fn(&sqlCardRepository{db: tx, read: tx})
+	})
+}
+
+// Delete moves the card to the trash, which hides it from every other
+// method until it is restored or purged. Unless version is 0, the card
+// must still be at that version.
+func (r *sqlCardRepository) Delete(id, userID, version int) error {
+	return inTx(r.db, func(tx DBTX) error {
+		now := db.Time(time.Now())
+		query := `UPDATE flashcards SET deleted_at = ?, ` + bumpVersion + ` WHERE id = ? AND user_id = ? AND deleted_at IS NULL`
+		args := []interface{}{now, now, id, userID}
+		if version != 0 {
+			query += ` AND version = ?`
+			args = append(args, version)
 		}
-		card.Repetitions++
-		card.EF += (0.1 - float64(5-quality)*(0.08+float64(5-quality)*0.02))
-		if card.EF < 1.3 {
-			card.EF = 1.3
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return err
 		}
-	}
+		if n, _ := result.RowsAffected(); n == 0 && version != 0 {
+			// Deleting a missing card is not an error, a changed one is.
+			if _, err := getCard(tx, id, userID); err == nil {
+				return ErrVersionConflict
+			}
+		}
+		return nil
+	})
+}
 
-	nextReview := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, card.Interval)
+// ListTrash returns the user's trashed cards, most recently deleted first.
+func (r *sqlCardRepository) ListTrash(userID int) ([]Flashcard, error) {
+	query := `SELECT ` + cardColumns + ` FROM flashcards WHERE user_id = ? AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC`
+	return r.queryCards(query, userID)
+}
+
+func (r *sqlCardRepository) Restore(id, userID int) error {
+	query := `UPDATE flashcards SET deleted_at = NULL, ` + bumpVersion + ` WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL`
+	return r.execCard(query, db.Time(time.Now()), id, userID)
+}
 
-	query := `UPDATE flashcards SET repetitions = ?, interval = ?, ef = ?, next_review = ? 
-			WHERE id = ? AND user_id = ?`
-	_, err = db.DB.Exec(query, card.Repetitions, card.Interval, card.EF, nextReview.Format(timeFormat), id, userID)
+// PurgeTrash permanently deletes the cards of all users that were trashed
+// before cutoff and returns how many were deleted.
+func (r *sqlCardRepository) PurgeTrash(cutoff time.Time) (int, error) {
+	var n int64
+	err := inTx(r.db, func(tx DBTX) error {
+		query := `DELETE FROM card_revisions WHERE card_id IN (SELECT id FROM flashcards WHERE deleted_at < ?)`
+		if _, err := tx.Exec(query, db.Time(cutoff)); err != nil {
+			return err
+		}
+		result, err := tx.Exec(`DELETE FROM flashcards WHERE deleted_at < ?`, db.Time(cutoff))
+		if err != nil {
+			return err
+		}
+		n, _ = result.RowsAffected()
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to update flashcard: %w", err)
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
 	}
-	return nil
+	return int(n), nil
 }
 
-func Delete(id, userID int) error {
-	query := `DELETE FROM flashcards WHERE id = ? AND user_id = ?`
-	_, err := db.DB.Exec(query, id, userID)
-	return err
+// Duplicate is an existing card that likely describes the same word.
+type Duplicate struct {
+	ID      int    `json:"id"`
+	Word    string `json:"word"`
+	Meaning string `json:"meaning"`
+	// Reason is "exact", "normalized" or "lemma".
+	Reason string `json:"reason"`
 }
 
-func ExistsByWord(userID int, word string) (bool, error) {
-	var count int
-	query := `SELECT COUNT(*) FROM flashcards WHERE user_id = ? AND LOWER(word) = ?`
-	err := db.DB.QueryRow(query, userID, strings.ToLower(word)).Scan(&count)
-	return count > 0, err
+// BlockingDuplicates returns those of duplicates that keep a card with
+// meaning from being created, and whether one of them has the very same
+// word and meaning, which force never overrides. The other duplicates only
+// block without force, e.g. so that a homonym can be added.
+func BlockingDuplicates(duplicates []Duplicate, meaning string, force bool) ([]Duplicate, bool) {
+	for _, d := range duplicates {
+		if d.Reason == "exact" && strings.EqualFold(strings.TrimSpace(d.Meaning), strings.TrimSpace(meaning)) {
+			return []Duplicate{d}, true
+		}
+	}
+	if force {
+		return nil, false
+	}
+	return duplicates, false
+}
+
+// duplicateMatcher decides whether existing cards duplicate a new word.
+type duplicateMatcher struct {
+	word       string
+	normalized string
+	lemmas     map[string]bool
+	lemmatizer lemma.Lemmatizer
+}
+
+func newDuplicateMatcher(word string, lemmatizer lemma.Lemmatizer) duplicateMatcher {
+	m := duplicateMatcher{
+		word:       word,
+		normalized: lemma.Normalize(word),
+		lemmas:     make(map[string]bool),
+		lemmatizer: lemmatizer,
+	}
+	for _, l := range lemmatizer.Lemmas(word) {
+		m.lemmas[l] = true
+	}
+	return m
+}
+
+// reason returns why existing duplicates the word, or "" if it does not.
+func (m duplicateMatcher) reason(existing string) string {
+	switch {
+	case strings.EqualFold(existing, m.word):
+		return "exact"
+	case lemma.Normalize(existing) == m.normalized:
+		return "normalized"
+	}
+	for _, l := range m.lemmatizer.Lemmas(existing) {
+		if m.lemmas[l] {
+			return "lemma"
+		}
+	}
+	return ""
 }
 
-func GetAllTags(userID int) ([]string, error) {
-	query := `SELECT tags FROM flashcards WHERE user_id = ? AND tags != ''`
-	rows, err := db.DB.Query(query, userID)
+// FindDuplicates returns the user's cards whose word matches word exactly,
+// after Unicode normalization, or shares a lemma with it.
+func (r *sqlCardRepository) FindDuplicates(userID int, word string, lemmatizer lemma.Lemmatizer) ([]Duplicate, error) {
+	rows, err := r.read.Query(`SELECT id, word, meaning FROM flashcards WHERE user_id = ? AND deleted_at IS NULL`, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query tags: %w", err)
+		return nil, fmt.Errorf("failed to query flashcards: %w", err)
 	}
 	defer rows.Close()
 
-	uniqueTags := make(map[string]bool)
+	matcher := newDuplicateMatcher(word, lemmatizer)
+	var duplicates []Duplicate
+	for rows.Next() {
+		var d Duplicate
+		if err := rows.Scan(&d.ID, &d.Word, &d.Meaning); err != nil {
+			return nil, fmt.Errorf("failed to scan flashcard: %w", err)
+		}
+		if d.Reason = matcher.reason(d.Word); d.Reason != "" {
+			duplicates = append(duplicates, d)
+		}
+	}
+	return duplicates, rows.Err()
+}
 
+func (r *sqlCardRepository) GetAllTags(userID int) ([]string, error) {
+	query := `SELECT tags FROM flashcards WHERE user_id = ? AND deleted_at IS NULL AND tags != ''`
+	rows, err := r.read.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tagLists []string
 	for rows.Next() {
 		var tagsStr string
 		if err := rows.Scan(&tagsStr); err != nil {
 			return nil, fmt.Errorf("failed to scan tags: %w", err)
 		}
+		tagLists = append(tagLists, tagsStr)
+	}
+	return uniqueTags(tagLists), rows.Err()
+}
 
-		tags := strings.Split(tagsStr, ",")
-		for _, tag := range tags {
+// uniqueTags splits comma-separated tag lists into distinct tags.
+func uniqueTags(tagLists []string) []string {
+	unique := make(map[string]bool)
+	for _, tagsStr := range tagLists {
+		for _, tag := range strings.Split(tagsStr, ",") {
 			trimmedTag := strings.TrimSpace(tag)
 			if trimmedTag != "" {
-				uniqueTags[trimmedTag] = true
+				unique[trimmedTag] = true
 			}
 		}
 	}
 
 	var result []string
-	for tag := range uniqueTags {
+	for tag := range unique {
 		result = append(result, tag)
 	}
+	return result
+}
 
-	return result, nil
-}
\ No newline at end of file
+// GetUserWords returns the lowercased words of all the user's cards.
+func (r *sqlCardRepository) GetUserWords(userID int) (map[string]bool, error) {
+	rows, err := r.read.Query(`SELECT word FROM flashcards WHERE user_id = ? AND deleted_at IS NULL`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query words: %w", err)
+	}
+	defer rows.Close()
+
+	words := make(map[string]bool)
+	for rows.Next() {
+		var word string
+		if err := rows.Scan(&word); err != nil {
+			return nil, fmt.Errorf("failed to scan word: %w", err)
+		}
+		words[strings.ToLower(strings.TrimSpace(word))] = true
+	}
+	return words, rows.Err()
+}
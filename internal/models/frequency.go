@@ -0,0 +1,117 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/db"
+)
+
+type FrequencyList struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	Language   string    `json:"language"`
+	WordCount  int       `json:"word_count"`
+	SourceHash string    `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type FrequencyWord struct {
+	Rank int    `json:"rank"`
+	Word string `json:"word"`
+}
+
+type sqlFrequencyRepository struct {
+	db   DBTX
+	read DBTX
+}
+
+// NewSQLFrequencyRepository returns a FrequencyRepository that writes to db
+// and runs plain queries on read.
+func NewSQLFrequencyRepository(db, read DBTX) FrequencyRepository {
+	return &sqlFrequencyRepository{db: db, read: read}
+}
+
+// Import replaces the list with the given name by words, ranked
+// in the order given.
+func (r *sqlFrequencyRepository) Import(name, language, sourceHash string, words []string) error {
+	return inTx(r.db, func(tx DBTX) error {
+		if _, err := tx.Exec(`DELETE FROM frequency_words WHERE list_id IN (SELECT id FROM frequency_lists WHERE name = ?)`, name); err != nil {
+			return fmt.Errorf("failed to clear frequency list: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM frequency_lists WHERE name = ?`, name); err != nil {
+			return fmt.Errorf("failed to clear frequency list: %w", err)
+		}
+
+		var listID int
+		err := tx.QueryRow(`INSERT INTO frequency_lists (name, language, word_count, source_hash, created_at) VALUES (?, ?, ?, ?, ?) RETURNING id`,
+			name, language, len(words), sourceHash, db.Time(time.Now())).Scan(&listID)
+		if err != nil {
+			return fmt.Errorf("failed to save frequency list: %w", err)
+		}
+
+		stmt, err := tx.Prepare(`INSERT INTO frequency_words (list_id, rank, word) VALUES (?, ?, ?)`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare insert: %w", err)
+		}
+		defer stmt.Close()
+		for i, w := range words {
+			if _, err := stmt.Exec(listID, i+1, w); err != nil {
+				return fmt.Errorf("failed to save word %q: %w", w, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *sqlFrequencyRepository) List() ([]FrequencyList, error) {
+	rows, err := r.read.Query(`SELECT id, name, language, word_count, source_hash, created_at FROM frequency_lists ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query frequency lists: %w", err)
+	}
+	defer rows.Close()
+
+	var lists []FrequencyList
+	for rows.Next() {
+		var l FrequencyList
+		if err := rows.Scan(&l.ID, &l.Name, &l.Language, &l.WordCount, &l.SourceHash, (*db.Time)(&l.CreatedAt)); err != nil {
+			return nil, fmt.Errorf("failed to scan frequency list: %w", err)
+		}
+		lists = append(lists, l)
+	}
+	return lists, rows.Err()
+}
+
+// Get returns the list by name; ok is false if it does not exist.
+func (r *sqlFrequencyRepository) Get(name string) (FrequencyList, bool, error) {
+	var l FrequencyList
+	err := r.read.QueryRow(`SELECT id, name, language, word_count, source_hash, created_at FROM frequency_lists WHERE name = ?`, name).
+		Scan(&l.ID, &l.Name, &l.Language, &l.WordCount, &l.SourceHash, (*db.Time)(&l.CreatedAt))
+	if errors.Is(err, sql.ErrNoRows) {
+		return l, false, nil
+	}
+	if err != nil {
+		return l, false, fmt.Errorf("failed to get frequency list: %w", err)
+	}
+	return l, true, nil
+}
+
+func (r *sqlFrequencyRepository) Words(listID int) ([]FrequencyWord, error) {
+	rows, err := r.read.Query(`SELECT rank, word FROM frequency_words WHERE list_id = ? ORDER BY rank`, listID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query frequency words: %w", err)
+	}
+	defer rows.Close()
+
+	var words []FrequencyWord
+	for rows.Next() {
+		var w FrequencyWord
+		if err := rows.Scan(&w.Rank, &w.Word); err != nil {
+			return nil, fmt.Errorf("failed to scan frequency word: %w", err)
+		}
+		words = append(words, w)
+	}
+	return words, rows.Err()
+}
@@ -0,0 +1,62 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/Danyarbrg/flashCards/internal/db"
+	"github.com/Danyarbrg/flashCards/internal/db/dbtest"
+	"github.com/Danyarbrg/flashCards/internal/models"
+)
+
+func TestMemoryFrequency(t *testing.T) {
+	testFrequency(t, models.NewMemoryFrequencyRepository())
+}
+
+func TestSQLiteFrequency(t *testing.T) {
+	testFrequency(t, models.NewSQLFrequencyRepository(db.DB, db.Read))
+}
+
+func TestPostgresFrequency(t *testing.T) {
+	dbtest.UsePostgres(t)
+	testFrequency(t, models.NewSQLFrequencyRepository(db.DB, db.Read))
+}
+
+func testFrequency(t *testing.T, lists models.FrequencyRepository) {
+	name := "xx-" + newEmail()
+	if _, ok, err := lists.Get(name); err != nil || ok {
+		t.Fatalf("missing list: ok = %v (err %v)", ok, err)
+	}
+	if err := lists.Import(name, "xx", "hash1", []string{"the", "of", "and"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := lists.Import(name, "xx", "hash2", []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, ok, err := lists.Get(name)
+	if err != nil || !ok || list.Language != "xx" || list.WordCount != 2 || list.SourceHash != "hash2" {
+		t.Fatalf("Get = %+v, %v (err %v)", list, ok, err)
+	}
+	words, err := lists.Words(list.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.FrequencyWord{{Rank: 1, Word: "a"}, {Rank: 2, Word: "b"}}
+	if len(words) != len(want) || words[0] != want[0] || words[1] != want[1] {
+		t.Errorf("Words = %+v, want %+v", words, want)
+	}
+
+	all, err := lists.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := 0
+	for _, l := range all {
+		if l.Name == name {
+			found++
+		}
+	}
+	if found != 1 {
+		t.Errorf("List has %d lists named %s, want 1", found, name)
+	}
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/db"
+)
+
+// GetIdentityUserID returns the ID of the user linked to an external
+// identity.
+func (r *sqlUserRepository) GetIdentityUserID(issuer, subject string) (int, error) {
+	var userID int
+	query := `SELECT user_id FROM user_identities WHERE issuer = ? AND subject = ?`
+	err := r.read.QueryRow(query, issuer, subject).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get identity: %w", err)
+	}
+	return userID, nil
+}
+
+// LinkIdentity attaches an external identity to a user.
+func (r *sqlUserRepository) LinkIdentity(userID int, issuer, subject, email string) error {
+	query := `INSERT INTO user_identities (user_id, issuer, subject, email, created_at) VALUES (?, ?, ?, ?, ?)`
+	_, err := r.db.Exec(query, userID, issuer, subject, email, db.Time(time.Now()))
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	return nil
+}
+
+// ClaimUnverifiedUser hands an account whose email was never verified to
+// the owner of that email. The password is replaced, and the sessions,
+// access tokens and two-factor setup someone else may have created with
+// the old one are removed. It all happens in one transaction, so the
+// account is never left half claimed.
+func (r *sqlUserRepository) ClaimUnverifiedUser(userID int, password string) error {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return inTx(r.db, func(tx DBTX) error {
+		now := db.Time(time.Now())
+		steps := []struct {
+			query string
+			args  []any
+		}{
+			{`UPDATE users SET password_hash = ?, email_verified_at = ?, totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0 WHERE id = ?`,
+				[]any{string(hashedPassword), now, userID}},
+			{`DELETE FROM recovery_codes WHERE user_id = ?`, []any{userID}},
+			{`UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`, []any{now, userID}},
+			{`UPDATE access_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`, []any{now, userID}},
+		}
+		for _, step := range steps {
+			if _, err := tx.Exec(step.query, step.args...); err != nil {
+				return fmt.Errorf("failed to claim user: %w", err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,46 @@
+package models_test
+
+import (
+	"crypto/rand"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/db"
+	"github.com/Danyarbrg/flashCards/internal/models"
+)
+
+// TestMain migrates a fresh SQLite database for the SQL repositories.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "flashcards-models")
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts := db.SQLiteOptions{JournalMode: "WAL", BusyTimeout: 5 * time.Second, ForeignKeys: true, ReadConns: 4}
+	if err := db.InitDB(filepath.Join(dir, "test.db"), opts); err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	db.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newEmail returns an address no other test uses.
+func newEmail() string {
+	return strings.ToLower(rand.Text()) + "@example.com"
+}
+
+// newUser registers a user to own the cards of a test.
+func newUser(t *testing.T, users models.UserRepository) models.User {
+	t.Helper()
+	user, err := users.Register(newEmail(), "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
@@ -0,0 +1,1218 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/lemma"
+)
+
+// memoryCardRepository keeps cards in memory. It is meant for tests.
+type memoryCardRepository struct {
+	mu *sync.Mutex
+	// inTx is set on the repository passed to a transaction, which already
+	// holds mu.
+	inTx bool
+	*memoryCards
+}
+
+// memoryCards is the data of a memoryCardRepository, shared with the
+// repository of a running transaction.
+type memoryCards struct {
+	nextID         int
+	cards          map[int]Flashcard
+	nextRevisionID int
+	revisions      []CardRevision
+}
+
+func NewMemoryCardRepository() CardRepository {
+	return &memoryCardRepository{
+		mu:          new(sync.Mutex),
+		memoryCards: &memoryCards{nextID: 1, cards: make(map[int]Flashcard), nextRevisionID: 1},
+	}
+}
+
+// lock locks the repository and returns the function that unlocks it.
+// Inside a transaction the lock is already held.
+func (r *memoryCardRepository) lock() func() {
+	if r.inTx {
+		return func() {}
+	}
+	r.mu.Lock()
+	return r.mu.Unlock
+}
+
+func (r *memoryCardRepository) Create(f *Flashcard) error {
+	if err := f.validate(); err != nil {
+		return err
+	}
+	defer r.lock()()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	f.ID = r.nextID
+	f.NextReview = now
+	f.CreatedAt = now
+	f.Interval = 1
+	f.Repetitions = 0
+	f.EF = 2.5
+	f.Version = 1
+	f.UpdatedAt = now
+	r.nextID++
+	r.cards[f.ID] = *f
+	r.addRevision(*f, f.UserID, now)
+	return nil
+}
+
+// addRevision records the card's content. The caller holds r.mu.
+func (r *memoryCardRepository) addRevision(f Flashcard, userID int, at time.Time) {
+	r.revisions = append(r.revisions, CardRevision{
+		ID:        r.nextRevisionID,
+		CardID:    f.ID,
+		UserID:    userID,
+		Word:      f.Word,
+		Meaning:   f.Meaning,
+		Example:   f.Example,
+		Tags:      f.Tags,
+		CreatedAt: at,
+	})
+	r.nextRevisionID++
+}
+
+// update sets the card's fields and records a revision, unless nothing
+// changed. The caller holds r.mu.
+func (r *memoryCardRepository) update(f Flashcard, userID int, word, meaning, example, tags string) Flashcard {
+	if f.Word == word && f.Meaning == meaning && f.Example == example && f.Tags == tags {
+		return f
+	}
+	f.Word, f.Meaning, f.Example, f.Tags = word, meaning, example, tags
+	touch(&f)
+	r.cards[f.ID] = f
+	r.addRevision(f, userID, f.UpdatedAt)
+	return f
+}
+
+// touch marks the card as changed.
+func touch(f *Flashcard) {
+	f.Version++
+	f.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+}
+
+func (r *memoryCardRepository) Update(id, userID, version int, word, meaning, example, tags string) (Flashcard, error) {
+	defer r.lock()()
+
+	f, ok := r.cards[id]
+	if !ok || f.UserID != userID || f.DeletedAt != nil {
+		return Flashcard{}, ErrCardNotFound
+	}
+	if version != 0 && f.Version != version {
+		return Flashcard{}, ErrVersionConflict
+	}
+	return r.update(f, userID, word, meaning, example, tags), nil
+}
+
+func (r *memoryCardRepository) GetByID(id, userID int) (Flashcard, error) {
+	defer r.lock()()
+
+	f, ok := r.cards[id]
+	if !ok || f.UserID != userID || f.DeletedAt != nil {
+		return Flashcard{}, ErrCardNotFound
+	}
+	return f, nil
+}
+
+// userCards returns the user's cards that are not in the trash, ordered by
+// ID. The caller holds r.mu.
+func (r *memoryCardRepository) userCards(userID int) []Flashcard {
+	var cards []Flashcard
+	for _, f := range r.cards {
+		if f.UserID == userID && f.DeletedAt == nil {
+			cards = append(cards, f)
+		}
+	}
+	sort.Slice(cards, func(i, j int) bool { return cards[i].ID < cards[j].ID })
+	return cards
+}
+
+func (r *memoryCardRepository) List(userID, limit, offset int, sortBy, order, tagFilter string) ([]Flashcard, error) {
+	defer r.lock()()
+
+	var cards []Flashcard
+	for _, f := range r.userCards(userID) {
+		if tagFilter == "" || strings.Contains(strings.ToLower(f.Tags), strings.ToLower(tagFilter)) {
+			cards = append(cards, f)
+		}
+	}
+
+	less := map[string]func(a, b Flashcard) bool{
+		"created":     func(a, b Flashcard) bool { return a.CreatedAt.Before(b.CreatedAt) },
+		"repetitions": func(a, b Flashcard) bool { return a.Repetitions < b.Repetitions },
+		"ef":          func(a, b Flashcard) bool { return a.EF < b.EF },
+		"next_review": func(a, b Flashcard) bool { return a.NextReview.Before(b.NextReview) },
+	}
+	cmp, ok := less[sortBy]
+	if !ok {
+		cmp = less["created"]
+	}
+	desc := strings.ToLower(order) == "desc"
+	sort.SliceStable(cards, func(i, j int) bool {
+		if desc {
+			return cmp(cards[j], cards[i])
+		}
+		return cmp(cards[i], cards[j])
+	})
+
+	if offset >= len(cards) {
+		return nil, nil
+	}
+	cards = cards[offset:]
+	if limit < len(cards) {
+		cards = cards[:limit]
+	}
+	return cards, nil
+}
+
+func (r *memoryCardRepository) GetDue(userID int) ([]Flashcard, error) {
+	defer r.lock()()
+
+	due := dueBefore()
+	var cards []Flashcard
+	for _, f := range r.userCards(userID) {
+		if !f.Suspended && f.NextReview.Before(due) {
+			cards = append(cards, f)
+		}
+	}
+	return cards, nil
+}
+
+func (r *memoryCardRepository) Review(id, userID, quality int) error {
+	defer r.lock()()
+
+	f, ok := r.cards[id]
+	if !ok || f.UserID != userID || f.DeletedAt != nil {
+		return ErrCardNotFound
+	}
+	f.ApplyReview(quality, time.Now())
+	touch(&f)
+	r.cards[id] = f
+	return nil
+}
+
+func (r *memoryCardRepository) Delete(id, userID, version int) error {
+	defer r.lock()()
+
+	if f, ok := r.cards[id]; ok && f.UserID == userID && f.DeletedAt == nil {
+		if version != 0 && f.Version != version {
+			return ErrVersionConflict
+		}
+		touch(&f)
+		deletedAt := f.UpdatedAt
+		f.DeletedAt = &deletedAt
+		r.cards[id] = f
+	}
+	return nil
+}
+
+func (r *memoryCardRepository) ListTrash(userID int) ([]Flashcard, error) {
+	defer r.lock()()
+
+	var cards []Flashcard
+	for _, f := range r.cards {
+		if f.UserID == userID && f.DeletedAt != nil {
+			cards = append(cards, f)
+		}
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		if !cards[i].DeletedAt.Equal(*cards[j].DeletedAt) {
+			return cards[i].DeletedAt.After(*cards[j].DeletedAt)
+		}
+		return cards[i].ID > cards[j].ID
+	})
+	return cards, nil
+}
+
+func (r *memoryCardRepository) Restore(id, userID int) error {
+	defer r.lock()()
+
+	f, ok := r.cards[id]
+	if !ok || f.UserID != userID || f.DeletedAt == nil {
+		return ErrCardNotFound
+	}
+	f.DeletedAt = nil
+	touch(&f)
+	r.cards[id] = f
+	return nil
+}
+
+func (r *memoryCardRepository) PurgeTrash(cutoff time.Time) (int, error) {
+	defer r.lock()()
+
+	n := 0
+	for id, f := range r.cards {
+		if f.DeletedAt != nil && f.DeletedAt.Before(cutoff) {
+			delete(r.cards, id)
+			n++
+		}
+	}
+	kept := r.revisions[:0]
+	for _, rev := range r.revisions {
+		if _, ok := r.cards[rev.CardID]; ok {
+			kept = append(kept, rev)
+		}
+	}
+	r.revisions = kept
+	return n, nil
+}
+
+func (r *memoryCardRepository) ListRevisions(id, userID int) ([]CardRevision, error) {
+	defer r.lock()()
+
+	if f, ok := r.cards[id]; !ok || f.UserID != userID || f.DeletedAt != nil {
+		return nil, ErrCardNotFound
+	}
+	var revisions []CardRevision
+	for _, rev := range r.revisions {
+		if rev.CardID == id {
+			revisions = append(revisions, rev)
+		}
+	}
+	return withChanges(revisions), nil
+}
+
+func (r *memoryCardRepository) Revert(id, userID, revisionID int) (Flashcard, error) {
+	defer r.lock()()
+
+	f, ok := r.cards[id]
+	if !ok || f.UserID != userID || f.DeletedAt != nil {
+		return Flashcard{}, ErrCardNotFound
+	}
+	for _, rev := range r.revisions {
+		if rev.ID == revisionID && rev.CardID == id {
+			return r.update(f, userID, rev.Word, rev.Meaning, rev.Example, rev.Tags), nil
+		}
+	}
+	return Flashcard{}, ErrRevisionNotFound
+}
+
+func (r *memoryCardRepository) SetSuspended(id, userID, version int, suspended bool) error {
+	defer r.lock()()
+
+	f, ok := r.cards[id]
+	if !ok || f.UserID != userID || f.DeletedAt != nil {
+		return ErrCardNotFound
+	}
+	if version != 0 && f.Version != version {
+		return ErrVersionConflict
+	}
+	f.Suspended = suspended
+	touch(&f)
+	r.cards[id] = f
+	return nil
+}
+
+func (r *memoryCardRepository) Reschedule(id, userID, version int, next time.Time) error {
+	defer r.lock()()
+
+	f, ok := r.cards[id]
+	if !ok || f.UserID != userID || f.DeletedAt != nil {
+		return ErrCardNotFound
+	}
+	if version != 0 && f.Version != version {
+		return ErrVersionConflict
+	}
+	f.NextReview = next
+	touch(&f)
+	r.cards[id] = f
+	return nil
+}
+
+func (r *memoryCardRepository) Search(userID int, q CardQuery) ([]Flashcard, error) {
+	defer r.lock()()
+
+	search := strings.ToLower(q.Search)
+	due := dueBefore()
+	var cards []Flashcard
+	for _, f := range r.userCards(userID) {
+		if search != "" && !strings.Contains(strings.ToLower(f.Word), search) &&
+			!strings.Contains(strings.ToLower(f.Meaning), search) {
+			continue
+		}
+		if q.Due && (f.Suspended || !f.NextReview.Before(due)) {
+			continue
+		}
+		if q.Suspended != nil && f.Suspended != *q.Suspended {
+			continue
+		}
+		cards = append(cards, f)
+	}
+	return q.filterTag(cards), nil
+}
+
+// Transaction holds the lock while fn runs, so other calls wait as they
+// would for SQLite's single writer, and puts back a copy of the cards and
+// revisions if fn fails.
+func (r *memoryCardRepository) Transaction(fn func(CardRepository) error) error {
+	if r.inTx {
+		return fn(r)
+	}
+	defer r.lock()()
+
+	saved := *r.memoryCards
+	saved.cards = make(map[int]Flashcard, len(r.cards))
+	for id, f := range r.cards {
+		saved.cards[id] = f
+	}
+	saved.revisions = append([]CardRevision(nil), r.revisions...)
+
+	if err := fn(&memoryCardRepository{mu: r.mu, inTx: true, memoryCards: r.memoryCards}); err != nil {
+		*r.memoryCards = saved
+		return err
+	}
+	return nil
+}
+
+func (r *memoryCardRepository) GetAllTags(userID int) ([]string, error) {
+	defer r.lock()()
+
+	var tagLists []string
+	for _, f := range r.userCards(userID) {
+		tagLists = append(tagLists, f.Tags)
+	}
+	return uniqueTags(tagLists), nil
+}
+
+func (r *memoryCardRepository) GetUserWords(userID int) (map[string]bool, error) {
+	defer r.lock()()
+
+	words := make(map[string]bool)
+	for _, f := range r.userCards(userID) {
+		words[strings.ToLower(strings.TrimSpace(f.Word))] = true
+	}
+	return words, nil
+}
+
+func (r *memoryCardRepository) FindDuplicates(userID int, word string, lemmatizer lemma.Lemmatizer) ([]Duplicate, error) {
+	defer r.lock()()
+
+	matcher := newDuplicateMatcher(word, lemmatizer)
+	var duplicates []Duplicate
+	for _, f := range r.userCards(userID) {
+		if reason := matcher.reason(f.Word); reason != "" {
+			duplicates = append(duplicates, Duplicate{ID: f.ID, Word: f.Word, Meaning: f.Meaning, Reason: reason})
+		}
+	}
+	return duplicates, nil
+}
+
+// memoryUserRepository keeps users in memory. It is meant for tests.
+type memoryUserRepository struct {
+	mu     sync.Mutex
+	nextID int
+	users  map[int]User
+
+	// nextRowID numbers sessions, access tokens and user tokens.
+	nextRowID     int
+	sessions      []memorySession
+	accessTokens  []memoryAccessToken
+	userTokens    []memoryUserToken
+	twoFactor     map[int]TwoFactor
+	recoveryCodes map[int]map[string]bool // code hash to whether it was used
+	identities    map[memoryIdentity]int
+}
+
+type memorySession struct {
+	Session
+	tokenHash    string
+	previousHash string
+}
+
+type memoryAccessToken struct {
+	AccessToken
+	tokenHash string
+}
+
+type memoryUserToken struct {
+	UserToken
+	tokenHash string
+	used      bool
+}
+
+type memoryIdentity struct {
+	issuer, subject string
+}
+
+func NewMemoryUserRepository() UserRepository {
+	return &memoryUserRepository{
+		nextID:        1,
+		users:         make(map[int]User),
+		nextRowID:     1,
+		twoFactor:     make(map[int]TwoFactor),
+		recoveryCodes: make(map[int]map[string]bool),
+		identities:    make(map[memoryIdentity]int),
+	}
+}
+
+// byEmail finds a user by email, ignoring case. The caller holds r.mu.
+func (r *memoryUserRepository) byEmail(email string) (User, bool) {
+	for _, u := range r.users {
+		if strings.EqualFold(u.Email, email) {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
+func (r *memoryUserRepository) Register(email, password string) (User, error) {
+	email = strings.TrimSpace(email)
+	if email == "" || password == "" {
+		return User{}, fmt.Errorf("email and password are required")
+	}
+	if err := ValidateEmail(email); err != nil {
+		return User{}, err
+	}
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return User{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.byEmail(email); ok {
+		return User{}, fmt.Errorf("failed to register user: %w", ErrEmailTaken)
+	}
+	user := User{ID: r.nextID, Email: email, PasswordHash: string(hashedPassword), Role: RoleUser}
+	r.nextID++
+	r.users[user.ID] = user
+	return user, nil
+}
+
+func (r *memoryUserRepository) Authenticate(email, password string) (User, error) {
+	user, err := r.GetByEmail(email)
+	if err != nil {
+		return user, fmt.Errorf("invalid email or password")
+	}
+	return checkUserPassword(user, password)
+}
+
+func (r *memoryUserRepository) GetByID(id int) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (r *memoryUserRepository) GetByEmail(email string) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.byEmail(strings.TrimSpace(email))
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// update applies fn to the stored user.
+func (r *memoryUserRepository) update(userID int, fn func(u *User) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return nil
+	}
+	if err := fn(&user); err != nil {
+		return err
+	}
+	r.users[userID] = user
+	return nil
+}
+
+func (r *memoryUserRepository) SetPassword(userID int, password string) error {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	return r.update(userID, func(u *User) error {
+		u.PasswordHash = string(hashedPassword)
+		return nil
+	})
+}
+
+func (r *memoryUserRepository) MarkEmailVerified(userID int) error {
+	return r.update(userID, func(u *User) error {
+		u.EmailVerified = true
+		return nil
+	})
+}
+
+func (r *memoryUserRepository) ChangeEmail(userID int, email string) error {
+	r.mu.Lock()
+	if other, ok := r.byEmail(email); ok && other.ID != userID {
+		r.mu.Unlock()
+		return ErrEmailTaken
+	}
+	r.mu.Unlock()
+
+	return r.update(userID, func(u *User) error {
+		u.Email = email
+		u.EmailVerified = true
+		return nil
+	})
+}
+
+func (r *memoryUserRepository) Delete(userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return ErrUserNotFound
+	}
+	delete(r.users, userID)
+	delete(r.twoFactor, userID)
+	delete(r.recoveryCodes, userID)
+	for identity, id := range r.identities {
+		if id == userID {
+			delete(r.identities, identity)
+		}
+	}
+	r.sessions = slices.DeleteFunc(r.sessions, func(s memorySession) bool { return s.UserID == userID })
+	r.accessTokens = slices.DeleteFunc(r.accessTokens, func(t memoryAccessToken) bool { return t.UserID == userID })
+	r.userTokens = slices.DeleteFunc(r.userTokens, func(t memoryUserToken) bool { return t.UserID == userID })
+	return nil
+}
+
+// nextRow returns the ID of a new session or token. The caller holds r.mu.
+func (r *memoryUserRepository) nextRow() int {
+	id := r.nextRowID
+	r.nextRowID++
+	return id
+}
+
+// exists fails like the SQL foreign keys when there is no user userID.
+// The caller holds r.mu.
+func (r *memoryUserRepository) exists(userID int) error {
+	if _, ok := r.users[userID]; !ok {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) CreateSession(userID int, tokenHash, userAgent, ip string, ttl time.Duration) (Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.exists(userID); err != nil {
+		return Session{}, fmt.Errorf("failed to create session: %w", err)
+	}
+	now := time.Now().UTC()
+	s := Session{
+		ID:         r.nextRow(),
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+	r.sessions = append(r.sessions, memorySession{Session: s, tokenHash: tokenHash})
+	return s, nil
+}
+
+// session returns the stored session with the given ID, or nil. The caller
+// holds r.mu.
+func (r *memoryUserRepository) session(id int) *memorySession {
+	for i := range r.sessions {
+		if r.sessions[i].ID == id {
+			return &r.sessions[i]
+		}
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) GetSession(id int) (Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.session(id)
+	if s == nil {
+		return Session{}, ErrSessionNotFound
+	}
+	return s.Session, nil
+}
+
+func (r *memoryUserRepository) RotateSession(oldHash, newHash string, ttl time.Duration) (Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	for i := range r.sessions {
+		s := &r.sessions[i]
+		switch oldHash {
+		case s.tokenHash:
+			if !s.Active() {
+				return Session{}, ErrSessionNotFound
+			}
+			s.tokenHash, s.previousHash = newHash, oldHash
+			s.LastUsedAt = now
+			s.ExpiresAt = now.Add(ttl)
+			return s.Session, nil
+		case s.previousHash:
+			if s.RevokedAt == nil {
+				s.RevokedAt = &now
+			}
+			return Session{}, ErrRefreshTokenReused
+		}
+	}
+	return Session{}, ErrSessionNotFound
+}
+
+func (r *memoryUserRepository) RevokeSession(id, userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.session(id)
+	if s == nil || s.UserID != userID || s.RevokedAt != nil {
+		return ErrSessionNotFound
+	}
+	now := time.Now().UTC()
+	s.RevokedAt = &now
+	return nil
+}
+
+func (r *memoryUserRepository) GetActiveSessions(userID int) ([]Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sessions []Session
+	for _, s := range r.sessions {
+		if s.UserID == userID && s.Active() {
+			sessions = append(sessions, s.Session)
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt) })
+	return sessions, nil
+}
+
+// revokeSessions revokes the user's sessions but keepID and returns how
+// many it revoked. The caller holds r.mu.
+func (r *memoryUserRepository) revokeSessions(userID, keepID int) int64 {
+	now := time.Now().UTC()
+	var n int64
+	for i := range r.sessions {
+		s := &r.sessions[i]
+		if s.UserID == userID && s.ID != keepID && s.RevokedAt == nil {
+			s.RevokedAt = &now
+			n++
+		}
+	}
+	return n
+}
+
+func (r *memoryUserRepository) RevokeOtherSessions(userID, keepID int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.revokeSessions(userID, keepID), nil
+}
+
+func (r *memoryUserRepository) TouchSession(id int, ip string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s := r.session(id); s != nil {
+		s.LastUsedAt = time.Now().UTC()
+		s.IP = ip
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) RevokeAllSessions(userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revokeSessions(userID, 0)
+	return nil
+}
+
+func (r *memoryUserRepository) CreateAccessToken(userID int, name, tokenHash string, scopes []string, expiresAt *time.Time) (AccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.exists(userID); err != nil {
+		return AccessToken{}, fmt.Errorf("failed to create access token: %w", err)
+	}
+	t := AccessToken{
+		ID:        r.nextRow(),
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: expiresAt,
+	}
+	r.accessTokens = append(r.accessTokens, memoryAccessToken{AccessToken: t, tokenHash: tokenHash})
+	return t, nil
+}
+
+// accessToken returns the stored token for which match is true, or nil.
+// The caller holds r.mu.
+func (r *memoryUserRepository) accessToken(match func(t memoryAccessToken) bool) *memoryAccessToken {
+	for i := range r.accessTokens {
+		if match(r.accessTokens[i]) {
+			return &r.accessTokens[i]
+		}
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) GetAccessTokenByHash(tokenHash string) (AccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := r.accessToken(func(t memoryAccessToken) bool { return t.tokenHash == tokenHash })
+	if t == nil {
+		return AccessToken{}, ErrAccessTokenNotFound
+	}
+	return t.AccessToken, nil
+}
+
+func (r *memoryUserRepository) GetAccessTokens(userID int) ([]AccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Newest first, as tokens are appended in the order they are created.
+	var tokens []AccessToken
+	for i := len(r.accessTokens) - 1; i >= 0; i-- {
+		if t := r.accessTokens[i]; t.UserID == userID && t.RevokedAt == nil {
+			tokens = append(tokens, t.AccessToken)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *memoryUserRepository) RevokeAccessToken(id, userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := r.accessToken(func(t memoryAccessToken) bool { return t.ID == id && t.UserID == userID && t.RevokedAt == nil })
+	if t == nil {
+		return ErrAccessTokenNotFound
+	}
+	now := time.Now().UTC()
+	t.RevokedAt = &now
+	return nil
+}
+
+// revokeAccessTokens revokes all of the user's tokens. The caller holds
+// r.mu.
+func (r *memoryUserRepository) revokeAccessTokens(userID int) {
+	now := time.Now().UTC()
+	for i := range r.accessTokens {
+		if t := &r.accessTokens[i]; t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+}
+
+func (r *memoryUserRepository) RevokeAllAccessTokens(userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revokeAccessTokens(userID)
+	return nil
+}
+
+func (r *memoryUserRepository) TouchAccessToken(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t := r.accessToken(func(t memoryAccessToken) bool { return t.ID == id }); t != nil {
+		now := time.Now().UTC()
+		t.LastUsedAt = &now
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) GetTwoFactor(userID int) (TwoFactor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.exists(userID); err != nil {
+		return TwoFactor{}, fmt.Errorf("failed to read two-factor settings: %w", err)
+	}
+	return r.twoFactor[userID], nil
+}
+
+func (r *memoryUserRepository) SetPendingTwoFactor(userID int, secret string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.exists(userID) == nil {
+		r.twoFactor[userID] = TwoFactor{Secret: secret}
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) EnableTwoFactor(userID int, step int64, recoveryCodeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.exists(userID); err != nil {
+		return fmt.Errorf("failed to save recovery code: %w", err)
+	}
+	tf := r.twoFactor[userID]
+	tf.Enabled = true
+	tf.LastStep = step
+	r.twoFactor[userID] = tf
+
+	codes := make(map[string]bool, len(recoveryCodeHashes))
+	for _, hash := range recoveryCodeHashes {
+		codes[hash] = false
+	}
+	r.recoveryCodes[userID] = codes
+	return nil
+}
+
+func (r *memoryUserRepository) DisableTwoFactor(userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.twoFactor, userID)
+	delete(r.recoveryCodes, userID)
+	return nil
+}
+
+func (r *memoryUserRepository) UseTOTPStep(userID int, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tf := r.twoFactor[userID]
+	if r.exists(userID) != nil || tf.LastStep >= step {
+		return false, nil
+	}
+	tf.LastStep = step
+	r.twoFactor[userID] = tf
+	return true, nil
+}
+
+func (r *memoryUserRepository) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	used, ok := r.recoveryCodes[userID][codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	r.recoveryCodes[userID][codeHash] = true
+	return true, nil
+}
+
+func (r *memoryUserRepository) GetIdentityUserID(issuer, subject string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID, ok := r.identities[memoryIdentity{issuer, subject}]
+	if !ok {
+		return 0, ErrUserNotFound
+	}
+	return userID, nil
+}
+
+func (r *memoryUserRepository) LinkIdentity(userID int, issuer, subject, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.exists(userID); err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	identity := memoryIdentity{issuer, subject}
+	if _, ok := r.identities[identity]; ok {
+		return fmt.Errorf("failed to link identity: %s is already linked", subject)
+	}
+	r.identities[identity] = userID
+	return nil
+}
+
+func (r *memoryUserRepository) ClaimUnverifiedUser(userID int, password string) error {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return nil
+	}
+	user.PasswordHash = string(hashedPassword)
+	user.EmailVerified = true
+	r.users[userID] = user
+	delete(r.twoFactor, userID)
+	delete(r.recoveryCodes, userID)
+	r.revokeSessions(userID, 0)
+	r.revokeAccessTokens(userID)
+	return nil
+}
+
+func (r *memoryUserRepository) CreateUserToken(userID int, purpose, tokenHash, data string, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.exists(userID); err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+	for i := range r.userTokens {
+		if t := &r.userTokens[i]; t.UserID == userID && t.Purpose == purpose {
+			t.used = true
+		}
+	}
+	t := UserToken{ID: r.nextRow(), UserID: userID, Purpose: purpose, Data: data, ExpiresAt: time.Now().UTC().Add(ttl)}
+	r.userTokens = append(r.userTokens, memoryUserToken{UserToken: t, tokenHash: tokenHash})
+	return nil
+}
+
+func (r *memoryUserRepository) ConsumeUserToken(purpose, tokenHash string) (UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	for i := range r.userTokens {
+		t := &r.userTokens[i]
+		if t.tokenHash == tokenHash && t.Purpose == purpose && !t.used && t.ExpiresAt.After(now) {
+			t.used = true
+			return t.UserToken, nil
+		}
+	}
+	return UserToken{}, ErrInvalidUserToken
+}
+
+// summary describes the user for administrators. The repository does not
+// see cards, so the card counts stay zero. The caller holds r.mu.
+func (r *memoryUserRepository) summary(user User) UserSummary {
+	s := UserSummary{User: user, TwoFactorEnabled: r.twoFactor[user.ID].Enabled}
+	for _, session := range r.sessions {
+		if session.UserID == user.ID && (s.LastActiveAt == nil || session.LastUsedAt.After(*s.LastActiveAt)) {
+			lastUsed := session.LastUsedAt
+			s.LastActiveAt = &lastUsed
+		}
+	}
+	return s
+}
+
+func (r *memoryUserRepository) ListUsers(search string, limit, offset int) ([]UserSummary, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	search = strings.ToLower(strings.TrimSpace(search))
+	var matching []User
+	for _, u := range r.users {
+		if strings.Contains(strings.ToLower(u.Email), search) {
+			matching = append(matching, u)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool { return matching[i].ID < matching[j].ID })
+
+	users := []UserSummary{}
+	for i := offset; i < len(matching) && i < offset+limit; i++ {
+		users = append(users, r.summary(matching[i]))
+	}
+	return users, len(matching), nil
+}
+
+func (r *memoryUserRepository) GetUserSummary(userID int) (UserSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return UserSummary{}, ErrUserNotFound
+	}
+	return r.summary(user), nil
+}
+
+// GetStats counts what the repository holds; card counts stay zero.
+func (r *memoryUserRepository) GetStats() (Stats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var s Stats
+	for _, u := range r.users {
+		s.Users++
+		if u.EmailVerified {
+			s.VerifiedUsers++
+		}
+		if u.Disabled {
+			s.DisabledUsers++
+		}
+		if u.IsAdmin() {
+			s.Admins++
+		}
+		if r.twoFactor[u.ID].Enabled {
+			s.TwoFactorUsers++
+		}
+	}
+	activeSince := time.Now().UTC().Add(-activeUserPeriod)
+	active := make(map[int]bool)
+	for _, session := range r.sessions {
+		if !session.LastUsedAt.Before(activeSince) {
+			active[session.UserID] = true
+		}
+		if session.Active() {
+			s.ActiveSessions++
+		}
+	}
+	s.ActiveUsers = len(active)
+	for _, t := range r.accessTokens {
+		if t.Active() {
+			s.AccessTokens++
+		}
+	}
+	return s, nil
+}
+
+// lastAdmin reports whether user is the only active admin. The caller
+// holds r.mu.
+func (r *memoryUserRepository) lastAdmin(user User) bool {
+	if !user.IsAdmin() || user.Disabled {
+		return false
+	}
+	for _, u := range r.users {
+		if u.ID != user.ID && u.IsAdmin() && !u.Disabled {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *memoryUserRepository) SetRole(userID int, role string) error {
+	if role != RoleUser && role != RoleAdmin {
+		return ErrInvalidRole
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	if role != RoleAdmin && r.lastAdmin(user) {
+		return ErrLastAdmin
+	}
+	user.Role = role
+	r.users[userID] = user
+	return nil
+}
+
+func (r *memoryUserRepository) DisableUser(userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	if r.lastAdmin(user) {
+		return ErrLastAdmin
+	}
+	user.Disabled = true
+	r.users[userID] = user
+	r.revokeSessions(userID, 0)
+	r.revokeAccessTokens(userID)
+	return nil
+}
+
+func (r *memoryUserRepository) EnableUser(userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	user.Disabled = false
+	r.users[userID] = user
+	return nil
+}
+
+func (r *memoryUserRepository) PromoteAdmins(emails []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, email := range emails {
+		email = strings.TrimSpace(email)
+		if email == "" {
+			continue
+		}
+		for id, u := range r.users {
+			if strings.EqualFold(u.Email, email) {
+				u.Role = RoleAdmin
+				r.users[id] = u
+			}
+		}
+	}
+	return nil
+}
+
+// memoryFrequencyRepository keeps frequency lists in memory. It is meant
+// for tests.
+type memoryFrequencyRepository struct {
+	mu     sync.Mutex
+	nextID int
+	lists  map[string]FrequencyList
+	words  map[int][]FrequencyWord
+}
+
+func NewMemoryFrequencyRepository() FrequencyRepository {
+	return &memoryFrequencyRepository{nextID: 1, lists: make(map[string]FrequencyList), words: make(map[int][]FrequencyWord)}
+}
+
+func (r *memoryFrequencyRepository) Import(name, language, sourceHash string, words []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if old, ok := r.lists[name]; ok {
+		delete(r.words, old.ID)
+	}
+	list := FrequencyList{ID: r.nextID, Name: name, Language: language, WordCount: len(words), SourceHash: sourceHash, CreatedAt: time.Now().UTC()}
+	r.nextID++
+	ranked := make([]FrequencyWord, len(words))
+	for i, w := range words {
+		ranked[i] = FrequencyWord{Rank: i + 1, Word: w}
+	}
+	r.lists[name] = list
+	r.words[list.ID] = ranked
+	return nil
+}
+
+func (r *memoryFrequencyRepository) List() ([]FrequencyList, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var lists []FrequencyList
+	for _, l := range r.lists {
+		lists = append(lists, l)
+	}
+	sort.Slice(lists, func(i, j int) bool { return lists[i].Name < lists[j].Name })
+	return lists, nil
+}
+
+func (r *memoryFrequencyRepository) Get(name string) (FrequencyList, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list, ok := r.lists[name]
+	return list, ok, nil
+}
+
+func (r *memoryFrequencyRepository) Words(listID int) ([]FrequencyWord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.words[listID]), nil
+}
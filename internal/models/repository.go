@@ -0,0 +1,126 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/lemma"
+)
+
+// DBTX is satisfied by both *sql.DB and *sql.Tx, so repositories can run
+// inside a transaction.
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+	Prepare(query string) (*sql.Stmt, error)
+}
+
+// CardRepository stores flashcards. Every method but PurgeTrash is scoped
+// to one user.
+type CardRepository interface {
+	Create(card *Flashcard) error
+	Update(id, userID, version int, word, meaning, example, tags string) (Flashcard, error)
+	GetByID(id, userID int) (Flashcard, error)
+	List(userID, limit, offset int, sortBy, order, tagFilter string) ([]Flashcard, error)
+	GetDue(userID int) ([]Flashcard, error)
+	Review(id, userID, quality int) error
+	Delete(id, userID, version int) error
+	ListTrash(userID int) ([]Flashcard, error)
+	Restore(id, userID int) error
+	PurgeTrash(cutoff time.Time) (int, error)
+	ListRevisions(id, userID int) ([]CardRevision, error)
+	Revert(id, userID, revisionID int) (Flashcard, error)
+	SetSuspended(id, userID, version int, suspended bool) error
+	Reschedule(id, userID, version int, next time.Time) error
+	Search(userID int, q CardQuery) ([]Flashcard, error)
+	Transaction(fn func(CardRepository) error) error
+	GetAllTags(userID int) ([]string, error)
+	GetUserWords(userID int) (map[string]bool, error)
+	FindDuplicates(userID int, word string, lemmatizer lemma.Lemmatizer) ([]Duplicate, error)
+}
+
+// UserRepository stores user accounts and everything that belongs to them:
+// sessions, access tokens, two-factor settings, linked identities and
+// emailed tokens. Keeping them together lets one transaction change them
+// all, as disabling or claiming an account does.
+type UserRepository interface {
+	Register(email, password string) (User, error)
+	Authenticate(email, password string) (User, error)
+	GetByID(id int) (User, error)
+	GetByEmail(email string) (User, error)
+	SetPassword(userID int, password string) error
+	MarkEmailVerified(userID int) error
+	ChangeEmail(userID int, email string) error
+	Delete(userID int) error
+
+	CreateSession(userID int, tokenHash, userAgent, ip string, ttl time.Duration) (Session, error)
+	GetSession(id int) (Session, error)
+	RotateSession(oldHash, newHash string, ttl time.Duration) (Session, error)
+	RevokeSession(id, userID int) error
+	GetActiveSessions(userID int) ([]Session, error)
+	RevokeOtherSessions(userID, keepID int) (int64, error)
+	TouchSession(id int, ip string) error
+	RevokeAllSessions(userID int) error
+
+	CreateAccessToken(userID int, name, tokenHash string, scopes []string, expiresAt *time.Time) (AccessToken, error)
+	GetAccessTokenByHash(tokenHash string) (AccessToken, error)
+	GetAccessTokens(userID int) ([]AccessToken, error)
+	RevokeAccessToken(id, userID int) error
+	RevokeAllAccessTokens(userID int) error
+	TouchAccessToken(id int) error
+
+	GetTwoFactor(userID int) (TwoFactor, error)
+	SetPendingTwoFactor(userID int, secret string) error
+	EnableTwoFactor(userID int, step int64, recoveryCodeHashes []string) error
+	DisableTwoFactor(userID int) error
+	UseTOTPStep(userID int, step int64) (bool, error)
+	UseRecoveryCode(userID int, codeHash string) (bool, error)
+
+	GetIdentityUserID(issuer, subject string) (int, error)
+	LinkIdentity(userID int, issuer, subject, email string) error
+	ClaimUnverifiedUser(userID int, password string) error
+
+	CreateUserToken(userID int, purpose, tokenHash, data string, ttl time.Duration) error
+	ConsumeUserToken(purpose, tokenHash string) (UserToken, error)
+
+	ListUsers(search string, limit, offset int) ([]UserSummary, int, error)
+	GetUserSummary(userID int) (UserSummary, error)
+	GetStats() (Stats, error)
+	SetRole(userID int, role string) error
+	DisableUser(userID int) error
+	EnableUser(userID int) error
+	PromoteAdmins(emails []string) error
+}
+
+// FrequencyRepository stores word frequency lists, which are shared by all
+// users.
+type FrequencyRepository interface {
+	Import(name, language, sourceHash string, words []string) error
+	List() ([]FrequencyList, error)
+	Get(name string) (FrequencyList, bool, error)
+	Words(listID int) ([]FrequencyWord, error)
+}
+
+// inTx runs fn in a transaction when q is a *sql.DB. If q already is a
+// transaction, fn joins it.
+func inTx(q DBTX, fn func(tx DBTX) error) error {
+	db, ok := q.(interface {
+		BeginTx(context.Context, *sql.TxOptions) (*sql.Tx, error)
+	})
+	if !ok {
+		return fn(q)
+	}
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
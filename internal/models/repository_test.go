@@ -0,0 +1,880 @@
+package models_test
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/db"
+	"github.com/Danyarbrg/flashCards/internal/db/dbtest"
+	"github.com/Danyarbrg/flashCards/internal/models"
+)
+
+// The repositories are checked against the same contract, so the memory
+// ones used by handler tests behave like the SQL ones the server uses.
+
+func TestMemoryRepositories(t *testing.T) {
+	testRepositories(t, func() (models.CardRepository, models.UserRepository) {
+		return models.NewMemoryCardRepository(), models.NewMemoryUserRepository()
+	})
+}
+
+func TestSQLiteRepositories(t *testing.T) {
+	testRepositories(t, func() (models.CardRepository, models.UserRepository) {
+		return models.NewSQLCardRepository(db.DB, db.Read), models.NewSQLUserRepository(db.DB, db.Read)
+	})
+}
+
+func TestPostgresRepositories(t *testing.T) {
+	dbtest.UsePostgres(t)
+	testRepositories(t, func() (models.CardRepository, models.UserRepository) {
+		return models.NewSQLCardRepository(db.DB, db.Read), models.NewSQLUserRepository(db.DB, db.Read)
+	})
+}
+
+// testRepositories runs the contract with repositories from newRepos. The
+// cards of each test belong to a user of its own, so the repositories may
+// share their data between tests.
+func testRepositories(t *testing.T, newRepos func() (models.CardRepository, models.UserRepository)) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, models.CardRepository, models.UserRepository)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"Update", testUpdate},
+		{"DeleteAndRestore", testDeleteAndRestore},
+		{"Review", testReview},
+		{"ConcurrentReviews", testConcurrentReviews},
+		{"ListAndDue", testListAndDue},
+		{"SuspendAndReschedule", testSuspendAndReschedule},
+		{"Revisions", testRevisions},
+		{"Search", testSearch},
+		{"TransactionRollback", testTransactionRollback},
+		{"TransactionKeepsOutsideWrites", testTransactionKeepsOutsideWrites},
+		{"Users", testUsers},
+		{"Sessions", testSessions},
+		{"AccessTokens", testAccessTokens},
+		{"TwoFactor", testTwoFactor},
+		{"Identities", testIdentities},
+		{"UserTokens", testUserTokens},
+		{"Admin", testAdmin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cards, users := newRepos()
+			tt.fn(t, cards, users)
+		})
+	}
+}
+
+// newCard creates a card owned by userID.
+func newCard(t *testing.T, cards models.CardRepository, userID int, word, tags string) models.Flashcard {
+	t.Helper()
+	card := models.Flashcard{UserID: userID, Word: word, Meaning: word + " meaning", Tags: tags}
+	if err := cards.Create(&card); err != nil {
+		t.Fatal(err)
+	}
+	return card
+}
+
+func getCard(t *testing.T, cards models.CardRepository, id, userID int) models.Flashcard {
+	t.Helper()
+	card, err := cards.GetByID(id, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return card
+}
+
+// checkReturned checks that a card returned by a change is the card as
+// stored afterwards.
+func checkReturned(t *testing.T, returned, stored models.Flashcard) {
+	t.Helper()
+	if returned.ID != stored.ID || returned.Version != stored.Version || returned.Meaning != stored.Meaning ||
+		returned.Tags != stored.Tags || !returned.UpdatedAt.Equal(stored.UpdatedAt) {
+		t.Errorf("returned card = %+v, stored card = %+v", returned, stored)
+	}
+}
+
+func testCreateAndGet(t *testing.T, cards models.CardRepository, users models.UserRepository) {
+	user := newUser(t, users)
+	created := newCard(t, cards, user.ID, "house", "home")
+	if created.ID == 0 || created.Version != 1 || created.Interval != 1 || created.EF != 2.5 {
+		t.Errorf("created card = %+v", created)
+	}
+
+	got := getCard(t, cards, created.ID, user.ID)
+	if got.Word != "house" || got.Meaning != "house meaning" || got.Tags != "home" || got.Version != 1 {
+		t.Errorf("GetByID = %+v", got)
+	}
+
+	other := newUser(t, users)
+	if _, err := cards.GetByID(created.ID, other.ID); !errors.Is(err, models.ErrCardNotFound) {
+		t.Errorf("another user's card: err = %v", err)
+	}
+	if err := cards.Create(&models.Flashcard{UserID: user.ID, Word: "no meaning"}); err == nil {
+		t.Error("card without a meaning was created")
+	}
+}
+
+func testUpdate(t *testing.T, cards models.CardRepository, users models.UserRepository) {
+	user := newUser(t, users)
+	card := newCard(t, cards, user.ID, "cat", "")
+
+	updated, err := cards.Update(card.ID, user.ID, card.Version, "cat", "a small animal", "", "pets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := getCard(t, cards, card.ID, user.ID)
+	if got.Meaning != "a small animal" || got.Tags != "pets" || got.Version != card.Version+1 {
+		t.Errorf("updated card = %+v", got)
+	}
+	checkReturned(t, updated, got)
+
+	_, err = cards.Update(card.ID, user.ID, card.Version, "cat", "stale", "", "")
+	if !errors.Is(err, models.ErrVersionConflict) {
+		t.Errorf("update of a stale version: err = %v", err)
+	}
+	unchanged, err := cards.Update(card.ID, user.ID, 0, "cat", "a small animal", "", "pets")
+	if err != nil {
+		t.Errorf("unconditional update: %v", err)
+	}
+	if v := getCard(t, cards, card.ID, user.ID).Version; v != got.Version {
+		t.Errorf("update without changes moved the version from %d to %d", got.Version, v)
+	}
+	checkReturned(t, unchanged, got)
+
+	other := newUser(t, users)
+	_, err = cards.Update(card.ID, other.ID, 0, "cat", "mine now", "", "")
+	if !errors.Is(err, models.ErrCardNotFound) {
+		t.Errorf("update of another user's card: err = %v", err)
+	}
+}
+
+func testDeleteAndRestore(t *testing.T, cards models.CardRepository, users models.UserRepository) {
+	user := newUser(t, users)
+	card := newCard(t, cards, user.ID, "dog", "")
+
+	if err := cards.Delete(card.ID, user.ID, card.Version+1); !errors.Is(err, models.ErrVersionConflict) {
+		t.Errorf("delete of a stale version: err = %v", err)
+	}
+	if err := cards.Delete(card.ID, user.ID, card.Version); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cards.GetByID(card.ID, user.ID); !errors.Is(err, models.ErrCardNotFound) {
+		t.Errorf("trashed card: err = %v", err)
+	}
+	if err := cards.Delete(card.ID, user.ID, 0); err != nil {
+		t.Errorf("deleting a trashed card again: %v", err)
+	}
+
+	trash, err := cards.ListTrash(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 1 || trash[0].ID != card.ID || trash[0].DeletedAt == nil {
+		t.Fatalf("trash = %+v", trash)
+	}
+
+	if err := cards.Restore(card.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := getCard(t, cards, card.ID, user.ID); got.DeletedAt != nil || got.Version != card.Version+2 {
+		t.Errorf("restored card = %+v", got)
+	}
+	if err := cards.Restore(card.ID, user.ID); !errors.Is(err, models.ErrCardNotFound) {
+		t.Errorf("restoring a card that is not in the trash: err = %v", err)
+	}
+
+	if err := cards.Delete(card.ID, user.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cards.PurgeTrash(time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if trash, err := cards.ListTrash(user.ID); err != nil || len(trash) != 0 {
+		t.Errorf("trash after purge = %+v (err %v)", trash, err)
+	}
+	if err := cards.Restore(card.ID, user.ID); !errors.Is(err, models.ErrCardNotFound) {
+		t.Errorf("restoring a purged card: err = %v", err)
+	}
+}
+
+func testReview(t *testing.T, cards models.CardRepository, users models.UserRepository) {
+	user := newUser(t, users)
+	card := newCard(t, cards, user.ID, "bird", "")
+
+	if err := cards.Review(card.ID, user.ID, 5); err != nil {
+		t.Fatal(err)
+	}
+	got := getCard(t, cards, card.ID, user.ID)
+	if got.Repetitions != 1 || got.Interval != 1 || got.EF <= 2.5 || got.Version != card.Version+1 {
+		t.Errorf("reviewed card = %+v", got)
+	}
+	if !got.NextReview.After(time.Now()) {
+		t.Errorf("next review %v is not in the future", got.NextReview)
+	}
+
+	if err := cards.Review(card.ID, newUser(t, users).ID, 5); !errors.Is(err, models.ErrCardNotFound) {
+		t.Errorf("review of another user's card: err = %v", err)
+	}
+}
+
+// Of concurrent reviews of one card, each is either counted or fails with
+// ErrVersionConflict; none is silently lost.
+func testConcurrentReviews(t *testing.T, cards models.CardRepository, users models.UserRepository) {
+	user := newUser(t, users)
+	card := newCard(t, cards, user.ID, "wind", "")
+
+	const n = 8
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- cards.Review(card.ID, user.ID, 5)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	reviewed := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			reviewed++
+		case !errors.Is(err, models.ErrVersionConflict):
+			t.Errorf("review: %v", err)
+		}
+	}
+	got := getCard(t, cards, card.ID, user.ID)
+	if reviewed == 0 || got.Repetitions != reviewed || got.Version != card.Version+reviewed {
+		t.Errorf("%d reviews succeeded, card = %+v", reviewed, got)
+	}
+}
+
+func testListAndDue(t *testing.T, cards models.CardRepository, users models.UserRepository) {
+	user := newUser(t, users)
+	a := newCard(t, cards, user.ID, "apple", "fruit")
+	b := newCard(t, cards, user.ID, "bean", "vegetable")
+	c := newCard(t, cards, user.ID, "cherry", "fruit")
+
+	list, err := cards.List(user.ID, 2, 0, "created", "asc", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := cardIDs(list); len(ids) != 2 {
+		t.Errorf("first page = %v", ids)
+	}
+	list, err = cards.List(user.ID, 10, 0, "created", "asc", "fruit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := cardIDs(list); !equalIDs(ids, a.ID, c.ID) {
+		t.Errorf("cards tagged fruit = %v", ids)
+	}
+
+	if err := cards.SetSuspended(a.ID, user.ID, 0, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := cards.Reschedule(b.ID, user.ID, 0, time.Now().AddDate(0, 0, 7)); err != nil {
+		t.Fatal(err)
+	}
+	due, err := cards.GetDue(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := cardIDs(due); !equalIDs(ids, c.ID) {
+		t.Errorf("due cards = %v, want only %d", ids, c.ID)
+	}
+
+	tags, err := cards.GetAllTags(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(tags)
+	if len(tags) != 2 || tags[0] != "fruit" || tags[1] != "vegetable" {
+		t.Errorf("tags = %v", tags)
+	}
+	words, err := cards.GetUserWords(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(words) != 3 || !words["cherry"] {
+		t.Errorf("words = %v", words)
+	}
+}
+
+func testSuspendAndReschedule(t *testing.T, cards models.CardRepository, users models.UserRepository) {
+	user := newUser(t, users)
+	card := newCard(t, cards, user.ID, "snow", "")
+
+	if err := cards.SetSuspended(card.ID, user.ID, card.Version+1, true); !errors.Is(err, models.ErrVersionConflict) {
+		t.Errorf("suspending a stale version: err = %v", err)
+	}
+	if err := cards.SetSuspended(card.ID, user.ID, card.Version, true); err != nil {
+		t.Fatal(err)
+	}
+	got := getCard(t, cards, card.ID, user.ID)
+	if !got.Suspended || got.Version != card.Version+1 {
+		t.Errorf("suspended card = %+v", got)
+	}
+
+	next := time.Now().AddDate(0, 0, 3).UTC().Truncate(time.Second)
+	if err := cards.Reschedule(card.ID, user.ID, card.Version, next); !errors.Is(err, models.ErrVersionConflict) {
+		t.Errorf("rescheduling a stale version: err = %v", err)
+	}
+	if err := cards.Reschedule(card.ID, user.ID, got.Version, next); err != nil {
+		t.Fatal(err)
+	}
+	if got := getCard(t, cards, card.ID, user.ID); !got.NextReview.Equal(next) {
+		t.Errorf("next review = %v, want %v", got.NextReview, next)
+	}
+
+	other := newUser(t, users)
+	if err := cards.SetSuspended(card.ID, other.ID, 0, false); !errors.Is(err, models.ErrCardNotFound) {
+		t.Errorf("suspending another user's card: err = %v", err)
+	}
+	if err := cards.Reschedule(card.ID+1000, user.ID, 1, next); !errors.Is(err, models.ErrCardNotFound) {
+		t.Errorf("rescheduling a missing card: err = %v", err)
+	}
+}
+
+func testRevisions(t *testing.T, cards models.CardRepository, users models.UserRepository) {
+	user := newUser(t, users)
+	card := newCard(t, cards, user.ID, "tree", "")
+	if _, err := cards.Update(card.ID, user.ID, 0, "tree", "a tall plant", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err := cards.ListRevisions(card.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 || revisions[0].Meaning != "a tall plant" {
+		t.Fatalf("revisions = %+v", revisions)
+	}
+	if change := revisions[0].Changes["meaning"]; change.Old != "tree meaning" || change.New != "a tall plant" {
+		t.Errorf("changes = %+v", revisions[0].Changes)
+	}
+
+	reverted, err := cards.Revert(card.ID, user.ID, revisions[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := getCard(t, cards, card.ID, user.ID)
+	if got.Meaning != "tree meaning" {
+		t.Errorf("reverted card = %+v", got)
+	}
+	checkReturned(t, reverted, got)
+	if revisions, err := cards.ListRevisions(card.ID, user.ID); err != nil || len(revisions) != 3 {
+		t.Errorf("revisions after revert = %d (err %v)", len(revisions), err)
+	}
+	if _, err := cards.Revert(card.ID, user.ID, revisions[1].ID+1000); !errors.Is(err, models.ErrRevisionNotFound) {
+		t.Errorf("revert to a missing revision: err = %v", err)
+	}
+}
+
+func testSearch(t *testing.T, cards models.CardRepository, users models.UserRepository) {
+	user := newUser(t, users)
+	a := newCard(t, cards, user.ID, "river", "nature, water")
+	b := newCard(t, cards, user.ID, "rain", "water")
+	newCard(t, cards, user.ID, "stone", "nature")
+	if err := cards.SetSuspended(b.ID, user.ID, 0, true); err != nil {
+		t.Fatal(err)
+	}
+
+	suspended := true
+	tests := []struct {
+		name  string
+		query models.CardQuery
+		want  []int
+	}{
+		{"tag", models.CardQuery{Tag: "water"}, []int{a.ID, b.ID}},
+		{"whole tag only", models.CardQuery{Tag: "wat"}, nil},
+		{"text", models.CardQuery{Search: "RIV"}, []int{a.ID}},
+		{"suspended", models.CardQuery{Suspended: &suspended}, []int{b.ID}},
+		{"due", models.CardQuery{Tag: "water", Due: true}, []int{a.ID}},
+	}
+	for _, tt := range tests {
+		got, err := cards.Search(user.ID, tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := cardIDs(got); !equalIDs(ids, tt.want...) {
+			t.Errorf("%s: got %v, want %v", tt.name, ids, tt.want)
+		}
+	}
+}
+
+func testTransactionRollback(t *testing.T, cards models.CardRepository, users models.UserRepository) {
+	user := newUser(t, users)
+	card := newCard(t, cards, user.ID, "moon", "")
+
+	failed := errors.New("failed")
+	err := cards.Transaction(func(tx models.CardRepository) error {
+		newCard(t, tx, user.ID, "sun", "")
+		if _, err := tx.Update(card.ID, user.ID, 0, "moon", "changed", "", ""); err != nil {
+			return err
+		}
+		if got := getCard(t, tx, card.ID, user.ID); got.Meaning != "changed" {
+			t.Errorf("the transaction does not see its own change: %+v", got)
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("err = %v", err)
+	}
+
+	if got := getCard(t, cards, card.ID, user.ID); got.Meaning != "moon meaning" || got.Version != card.Version {
+		t.Errorf("card after rollback = %+v", got)
+	}
+	if words, err := cards.GetUserWords(user.ID); err != nil || len(words) != 1 {
+		t.Errorf("words after rollback = %v (err %v)", words, err)
+	}
+
+	err = cards.Transaction(func(tx models.CardRepository) error {
+		_, err := tx.Update(card.ID, user.ID, 0, "moon", "committed", "", "")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := getCard(t, cards, card.ID, user.ID); got.Meaning != "committed" {
+		t.Errorf("card after commit = %+v", got)
+	}
+}
+
+// A write made outside a transaction while it runs survives the rollback.
+func testTransactionKeepsOutsideWrites(t *testing.T, cards models.CardRepository, users models.UserRepository) {
+	user := newUser(t, users)
+	inside := newCard(t, cards, user.ID, "left", "")
+	outside := newCard(t, cards, user.ID, "right", "")
+
+	done := make(chan error, 1)
+	err := cards.Transaction(func(tx models.CardRepository) error {
+		if _, err := tx.Update(inside.ID, user.ID, 0, "left", "changed", "", ""); err != nil {
+			return err
+		}
+		go func() {
+			_, err := cards.Update(outside.ID, user.ID, 0, "right", "outside", "", "")
+			done <- err
+		}()
+		// The outside write may wait for the transaction to end.
+		select {
+		case err := <-done:
+			done <- err
+		case <-time.After(100 * time.Millisecond):
+		}
+		return errors.New("failed")
+	})
+	if err == nil {
+		t.Fatal("the transaction did not fail")
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if got := getCard(t, cards, inside.ID, user.ID); got.Meaning != "left meaning" {
+		t.Errorf("card changed in the transaction = %+v", got)
+	}
+	if got := getCard(t, cards, outside.ID, user.ID); got.Meaning != "outside" {
+		t.Errorf("card changed outside the transaction = %+v", got)
+	}
+}
+
+func testUsers(t *testing.T, _ models.CardRepository, users models.UserRepository) {
+	email := newEmail()
+	user, err := users.Register(email, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == 0 || user.Email != email || user.Role != models.RoleUser || user.EmailVerified {
+		t.Errorf("registered user = %+v", user)
+	}
+	if _, err := users.Register(email, "password"); !errors.Is(err, models.ErrEmailTaken) {
+		t.Errorf("registering the same email again: err = %v", err)
+	}
+	if _, err := users.Register(strings.ToUpper(email), "password"); !errors.Is(err, models.ErrEmailTaken) {
+		t.Errorf("registering the same email in upper case: err = %v", err)
+	}
+	if _, err := users.Register("not an email", "password"); err == nil {
+		t.Error("invalid email was registered")
+	}
+
+	if _, err := users.Authenticate(email, "password"); err != nil {
+		t.Errorf("authenticate: %v", err)
+	}
+	if _, err := users.Authenticate(email, "wrong"); err == nil {
+		t.Error("wrong password was accepted")
+	}
+	if err := users.SetPassword(user.ID, "new password"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.Authenticate(email, "new password"); err != nil {
+		t.Errorf("authenticate with the new password: %v", err)
+	}
+
+	if err := users.MarkEmailVerified(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := users.GetByEmail(email); err != nil || !got.EmailVerified || got.ID != user.ID {
+		t.Errorf("GetByEmail = %+v (err %v)", got, err)
+	}
+	// Identity providers may not keep the case the user registered with.
+	if got, err := users.GetByEmail(" " + strings.ToUpper(email)); err != nil || got.ID != user.ID {
+		t.Errorf("GetByEmail in upper case = %+v (err %v)", got, err)
+	}
+	if _, err := users.Authenticate(strings.ToUpper(email), "new password"); err != nil {
+		t.Errorf("authenticate with the email in upper case: %v", err)
+	}
+
+	other := newUser(t, users)
+	if err := users.ChangeEmail(user.ID, other.Email); !errors.Is(err, models.ErrEmailTaken) {
+		t.Errorf("changing to a taken email: err = %v", err)
+	}
+	if err := users.ChangeEmail(user.ID, strings.ToUpper(other.Email)); !errors.Is(err, models.ErrEmailTaken) {
+		t.Errorf("changing to a taken email in upper case: err = %v", err)
+	}
+	if err := users.ChangeEmail(user.ID, strings.ToUpper(email)); err != nil {
+		t.Errorf("changing the case of the user's own email: %v", err)
+	}
+	changed := newEmail()
+	if err := users.ChangeEmail(user.ID, changed); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := users.GetByID(user.ID); err != nil || got.Email != changed {
+		t.Errorf("GetByID after email change = %+v (err %v)", got, err)
+	}
+
+	if err := users.Delete(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.GetByID(user.ID); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("deleted user: err = %v", err)
+	}
+	if err := users.Delete(user.ID); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("deleting a deleted user: err = %v", err)
+	}
+}
+
+func testSessions(t *testing.T, _ models.CardRepository, users models.UserRepository) {
+	user := newUser(t, users)
+	hash := newEmail()
+	s, err := users.CreateSession(user.ID, hash, "agent", "10.0.0.1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := users.GetSession(s.ID); err != nil || got.UserID != user.ID || got.IP != "10.0.0.1" || !got.Active() {
+		t.Errorf("GetSession = %+v (err %v)", got, err)
+	}
+	if err := users.TouchSession(s.ID, "10.0.0.2"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := users.GetSession(s.ID); got.IP != "10.0.0.2" {
+		t.Errorf("IP after touch = %q", got.IP)
+	}
+	if _, err := users.CreateSession(1<<30, newEmail(), "", "", time.Hour); err == nil {
+		t.Error("session created for a missing user")
+	}
+
+	rotated := newEmail()
+	if got, err := users.RotateSession(hash, rotated, time.Hour); err != nil || got.ID != s.ID {
+		t.Fatalf("RotateSession = %+v (err %v)", got, err)
+	}
+	if _, err := users.RotateSession(hash, newEmail(), time.Hour); !errors.Is(err, models.ErrRefreshTokenReused) {
+		t.Errorf("rotating a rotated token: err = %v", err)
+	}
+	if got, _ := users.GetSession(s.ID); got.Active() {
+		t.Error("session still active after its old token was reused")
+	}
+	if _, err := users.RotateSession(rotated, newEmail(), time.Hour); !errors.Is(err, models.ErrSessionNotFound) {
+		t.Errorf("rotating a revoked session: err = %v", err)
+	}
+
+	a, err := users.CreateSession(user.ID, newEmail(), "", "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := users.CreateSession(user.ID, newEmail(), "", "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := users.GetActiveSessions(user.ID); err != nil || len(got) != 2 {
+		t.Errorf("GetActiveSessions = %+v (err %v)", got, err)
+	}
+	if n, err := users.RevokeOtherSessions(user.ID, a.ID); err != nil || n != 1 {
+		t.Errorf("RevokeOtherSessions = %d (err %v)", n, err)
+	}
+	if err := users.RevokeSession(b.ID, user.ID); !errors.Is(err, models.ErrSessionNotFound) {
+		t.Errorf("revoking a revoked session: err = %v", err)
+	}
+	if err := users.RevokeSession(a.ID, user.ID+1); !errors.Is(err, models.ErrSessionNotFound) {
+		t.Errorf("revoking another user's session: err = %v", err)
+	}
+	if err := users.RevokeAllSessions(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := users.GetActiveSessions(user.ID); err != nil || len(got) != 0 {
+		t.Errorf("GetActiveSessions after revoking all = %+v (err %v)", got, err)
+	}
+}
+
+func testAccessTokens(t *testing.T, _ models.CardRepository, users models.UserRepository) {
+	user := newUser(t, users)
+	hash := newEmail()
+	token, err := users.CreateAccessToken(user.ID, "script", hash, []string{"cards:read", "cards:write"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := users.GetAccessTokenByHash(hash)
+	if err != nil || got.ID != token.ID || got.UserID != user.ID || len(got.Scopes) != 2 || !got.Active() {
+		t.Errorf("GetAccessTokenByHash = %+v (err %v)", got, err)
+	}
+	if err := users.TouchAccessToken(token.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := users.GetAccessTokenByHash(hash); got.LastUsedAt == nil {
+		t.Error("last use not recorded")
+	}
+	expired := time.Now().Add(-time.Hour)
+	if _, err := users.CreateAccessToken(user.ID, "old", newEmail(), []string{"cards:read"}, &expired); err != nil {
+		t.Fatal(err)
+	}
+	if tokens, err := users.GetAccessTokens(user.ID); err != nil || len(tokens) != 2 {
+		t.Errorf("GetAccessTokens = %+v (err %v)", tokens, err)
+	}
+
+	if err := users.RevokeAccessToken(token.ID, user.ID+1); !errors.Is(err, models.ErrAccessTokenNotFound) {
+		t.Errorf("revoking another user's token: err = %v", err)
+	}
+	if err := users.RevokeAccessToken(token.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.RevokeAccessToken(token.ID, user.ID); !errors.Is(err, models.ErrAccessTokenNotFound) {
+		t.Errorf("revoking a revoked token: err = %v", err)
+	}
+	if err := users.RevokeAllAccessTokens(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if tokens, err := users.GetAccessTokens(user.ID); err != nil || len(tokens) != 0 {
+		t.Errorf("GetAccessTokens after revoking all = %+v (err %v)", tokens, err)
+	}
+	if _, err := users.GetAccessTokenByHash(newEmail()); !errors.Is(err, models.ErrAccessTokenNotFound) {
+		t.Errorf("missing token: err = %v", err)
+	}
+}
+
+func testTwoFactor(t *testing.T, _ models.CardRepository, users models.UserRepository) {
+	user := newUser(t, users)
+	if tf, err := users.GetTwoFactor(user.ID); err != nil || tf != (models.TwoFactor{}) {
+		t.Errorf("GetTwoFactor of a new user = %+v (err %v)", tf, err)
+	}
+	if err := users.SetPendingTwoFactor(user.ID, "SECRET"); err != nil {
+		t.Fatal(err)
+	}
+	if tf, _ := users.GetTwoFactor(user.ID); tf.Secret != "SECRET" || tf.Enabled {
+		t.Errorf("pending two-factor = %+v", tf)
+	}
+	if err := users.EnableTwoFactor(user.ID, 10, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if tf, _ := users.GetTwoFactor(user.ID); !tf.Enabled || tf.LastStep != 10 {
+		t.Errorf("enabled two-factor = %+v", tf)
+	}
+	if ok, err := users.UseTOTPStep(user.ID, 10); err != nil || ok {
+		t.Errorf("reusing the enabling step = %v (err %v)", ok, err)
+	}
+	if ok, err := users.UseTOTPStep(user.ID, 11); err != nil || !ok {
+		t.Errorf("using the next step = %v (err %v)", ok, err)
+	}
+
+	for _, tt := range []struct {
+		code string
+		ok   bool
+	}{{"a", true}, {"a", false}, {"c", false}, {"b", true}} {
+		if ok, err := users.UseRecoveryCode(user.ID, tt.code); err != nil || ok != tt.ok {
+			t.Errorf("UseRecoveryCode(%q) = %v (err %v), want %v", tt.code, ok, err, tt.ok)
+		}
+	}
+
+	if err := users.EnableTwoFactor(user.ID, 12, []string{"c"}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := users.UseRecoveryCode(user.ID, "c"); !ok {
+		t.Error("new recovery code refused")
+	}
+	if err := users.DisableTwoFactor(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if tf, _ := users.GetTwoFactor(user.ID); tf.Enabled || tf.Secret != "" {
+		t.Errorf("disabled two-factor = %+v", tf)
+	}
+}
+
+func testIdentities(t *testing.T, _ models.CardRepository, users models.UserRepository) {
+	user := newUser(t, users)
+	subject := newEmail()
+	if _, err := users.GetIdentityUserID("https://issuer.test", subject); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("unlinked identity: err = %v", err)
+	}
+	if err := users.LinkIdentity(user.ID, "https://issuer.test", subject, user.Email); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := users.GetIdentityUserID("https://issuer.test", subject); err != nil || got != user.ID {
+		t.Errorf("GetIdentityUserID = %d (err %v)", got, err)
+	}
+	if err := users.LinkIdentity(newUser(t, users).ID, "https://issuer.test", subject, ""); err == nil {
+		t.Error("identity linked twice")
+	}
+
+	session, err := users.CreateSession(user.ID, newEmail(), "", "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.CreateAccessToken(user.ID, "script", newEmail(), []string{"cards:read"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.EnableTwoFactor(user.ID, 1, []string{"code"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.ClaimUnverifiedUser(user.ID, "claimed"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := users.Authenticate(user.Email, "claimed"); err != nil || !got.EmailVerified {
+		t.Errorf("claimed user = %+v (err %v)", got, err)
+	}
+	if s, _ := users.GetSession(session.ID); s.Active() {
+		t.Error("session survived the claim")
+	}
+	if tokens, _ := users.GetAccessTokens(user.ID); len(tokens) != 0 {
+		t.Errorf("access tokens survived the claim: %+v", tokens)
+	}
+	if tf, _ := users.GetTwoFactor(user.ID); tf.Enabled {
+		t.Error("two-factor survived the claim")
+	}
+}
+
+func testUserTokens(t *testing.T, _ models.CardRepository, users models.UserRepository) {
+	user := newUser(t, users)
+	first, second := newEmail(), newEmail()
+	if err := users.CreateUserToken(user.ID, models.TokenPasswordReset, first, "", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.CreateUserToken(user.ID, models.TokenPasswordReset, second, "data", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.ConsumeUserToken(models.TokenPasswordReset, first); !errors.Is(err, models.ErrInvalidUserToken) {
+		t.Errorf("superseded token: err = %v", err)
+	}
+	if _, err := users.ConsumeUserToken(models.TokenEmailChange, second); !errors.Is(err, models.ErrInvalidUserToken) {
+		t.Errorf("token of another purpose: err = %v", err)
+	}
+	got, err := users.ConsumeUserToken(models.TokenPasswordReset, second)
+	if err != nil || got.UserID != user.ID || got.Data != "data" {
+		t.Errorf("ConsumeUserToken = %+v (err %v)", got, err)
+	}
+	if _, err := users.ConsumeUserToken(models.TokenPasswordReset, second); !errors.Is(err, models.ErrInvalidUserToken) {
+		t.Errorf("consuming a token twice: err = %v", err)
+	}
+
+	expired := newEmail()
+	if err := users.CreateUserToken(user.ID, models.TokenEmailVerification, expired, "", -time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.ConsumeUserToken(models.TokenEmailVerification, expired); !errors.Is(err, models.ErrInvalidUserToken) {
+		t.Errorf("expired token: err = %v", err)
+	}
+}
+
+// testAdmin relies on no other test leaving an active admin behind.
+func testAdmin(t *testing.T, _ models.CardRepository, users models.UserRepository) {
+	a, b := newUser(t, users), newUser(t, users)
+	if err := users.PromoteAdmins([]string{" " + strings.ToUpper(a.Email), ""}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := users.GetByID(a.ID); !got.IsAdmin() {
+		t.Errorf("promoted user = %+v", got)
+	}
+	if err := users.SetRole(b.ID, "owner"); !errors.Is(err, models.ErrInvalidRole) {
+		t.Errorf("invalid role: err = %v", err)
+	}
+	if err := users.SetRole(1<<30, models.RoleAdmin); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("missing user: err = %v", err)
+	}
+	if err := users.SetRole(b.ID, models.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := users.CreateSession(a.ID, newEmail(), "", "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.DisableUser(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := users.GetSession(session.ID); s.Active() {
+		t.Error("session of a disabled user still active")
+	}
+	if err := users.SetRole(b.ID, models.RoleUser); !errors.Is(err, models.ErrLastAdmin) {
+		t.Errorf("demoting the last active admin: err = %v", err)
+	}
+	if err := users.DisableUser(b.ID); !errors.Is(err, models.ErrLastAdmin) {
+		t.Errorf("disabling the last active admin: err = %v", err)
+	}
+	if err := users.EnableUser(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.EnableUser(1 << 30); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("enabling a missing user: err = %v", err)
+	}
+
+	summary, err := users.GetUserSummary(a.ID)
+	if err != nil || summary.Email != a.Email || summary.Disabled || summary.LastActiveAt == nil {
+		t.Errorf("GetUserSummary = %+v (err %v)", summary, err)
+	}
+	list, total, err := users.ListUsers(strings.ToUpper(a.Email), 10, 0)
+	if err != nil || total != 1 || len(list) != 1 || list[0].ID != a.ID {
+		t.Errorf("ListUsers = %+v, %d (err %v)", list, total, err)
+	}
+	if list, total, err := users.ListUsers(a.Email, 10, 1); err != nil || total != 1 || len(list) != 0 {
+		t.Errorf("ListUsers past the end = %+v, %d (err %v)", list, total, err)
+	}
+	if stats, err := users.GetStats(); err != nil || stats.Users < 2 || stats.Admins < 2 {
+		t.Errorf("GetStats = %+v (err %v)", stats, err)
+	}
+
+	// Leave no admins behind for other tests.
+	if err := users.SetRole(a.ID, models.RoleUser); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Delete(b.ID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func cardIDs(cards []models.Flashcard) []int {
+	ids := make([]int, len(cards))
+	for i, f := range cards {
+		ids[i] = f.ID
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func equalIDs(got []int, want ...int) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	sort.Ints(want)
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
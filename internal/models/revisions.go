@@ -0,0 +1,145 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/db"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// CardRevision is a card's content after one edit. Changes lists the fields
+// that differ from the previous revision; the first revision of a card
+// lists every field that was set.
+type CardRevision struct {
+	ID        int                    `json:"id"`
+	CardID    int                    `json:"card_id"`
+	UserID    int                    `json:"user_id"`
+	UserEmail string                 `json:"user_email"`
+	Word      string                 `json:"word"`
+	Meaning   string                 `json:"meaning"`
+	Example   string                 `json:"example"`
+	Tags      string                 `json:"tags"`
+	CreatedAt time.Time              `json:"created_at"`
+	Changes   map[string]FieldChange `json:"changes"`
+}
+
+type FieldChange struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+func (r CardRevision) fields() map[string]string {
+	return map[string]string{"word": r.Word, "meaning": r.Meaning, "example": r.Example, "tags": r.Tags}
+}
+
+// withChanges fills in Changes of revisions ordered oldest first and
+// returns them newest first.
+func withChanges(revisions []CardRevision) []CardRevision {
+	var previous map[string]string
+	for i := range revisions {
+		current := revisions[i].fields()
+		revisions[i].Changes = make(map[string]FieldChange)
+		for field, value := range current {
+			if value != previous[field] {
+				revisions[i].Changes[field] = FieldChange{Old: previous[field], New: value}
+			}
+		}
+		previous = current
+	}
+	for i, j := 0, len(revisions)-1; i < j; i, j = i+1, j-1 {
+		revisions[i], revisions[j] = revisions[j], revisions[i]
+	}
+	return revisions
+}
+
+// addRevision records the card's current content as edited by userID.
+func addRevision(tx DBTX, card Flashcard, userID int, at time.Time) error {
+	query := `INSERT INTO card_revisions (card_id, user_id, word, meaning, example, tags, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	if _, err := tx.Exec(query, card.ID, userID, card.Word, card.Meaning, card.Example, card.Tags, db.Time(at)); err != nil {
+		return fmt.Errorf("failed to save revision: %w", err)
+	}
+	return nil
+}
+
+// updateCard sets the card's fields and records a revision, unless nothing
+// changed. It fails with ErrVersionConflict if the card was changed since
+// it was read.
+func updateCard(tx DBTX, card Flashcard, userID int, word, meaning, example, tags string) (Flashcard, error) {
+	if card.Word == word && card.Meaning == meaning && card.Example == example && card.Tags == tags {
+		return card, nil
+	}
+	now := time.Now()
+	query := `UPDATE flashcards SET word = ?, meaning = ?, example = ?, tags = ?, ` + bumpVersion + `
+		WHERE id = ? AND user_id = ? AND version = ?`
+	result, err := tx.Exec(query, word, meaning, example, tags, db.Time(now), card.ID, card.UserID, card.Version)
+	if err != nil {
+		return Flashcard{}, fmt.Errorf("failed to update flashcard: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return Flashcard{}, ErrVersionConflict
+	}
+	card.Word, card.Meaning, card.Example, card.Tags = word, meaning, example, tags
+	card.Version++
+	card.UpdatedAt = now.UTC().Truncate(time.Second)
+	return card, addRevision(tx, card, userID, now)
+}
+
+// ListRevisions returns the history of the card, newest first.
+func (r *sqlCardRepository) ListRevisions(id, userID int) ([]CardRevision, error) {
+	if _, err := getCard(r.read, id, userID); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT r.id, r.card_id, r.user_id, COALESCE(u.email, ''), r.word, r.meaning, r.example, r.tags, r.created_at
+		FROM card_revisions r LEFT JOIN users u ON u.id = r.user_id
+		WHERE r.card_id = ? ORDER BY r.id`
+	rows, err := r.read.Query(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []CardRevision
+	for rows.Next() {
+		var rev CardRevision
+		err := rows.Scan(&rev.ID, &rev.CardID, &rev.UserID, &rev.UserEmail, &rev.Word, &rev.Meaning,
+			&rev.Example, &rev.Tags, (*db.Time)(&rev.CreatedAt))
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan revision: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return withChanges(revisions), nil
+}
+
+// Revert sets the card's fields back to those of a revision and returns the
+// card as it now is. The revert is itself recorded as a new revision.
+func (r *sqlCardRepository) Revert(id, userID, revisionID int) (Flashcard, error) {
+	var updated Flashcard
+	err := inTx(r.db, func(tx DBTX) error {
+		card, err := getCard(tx, id, userID)
+		if err != nil {
+			return err
+		}
+
+		var rev CardRevision
+		query := `SELECT word, meaning, example, tags FROM card_revisions WHERE id = ? AND card_id = ?`
+		err = tx.QueryRow(query, revisionID, id).Scan(&rev.Word, &rev.Meaning, &rev.Example, &rev.Tags)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRevisionNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get revision: %w", err)
+		}
+		updated, err = updateCard(tx, card, userID, rev.Word, rev.Meaning, rev.Example, rev.Tags)
+		return err
+	})
+	return updated, err
+}
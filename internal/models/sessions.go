@@ -0,0 +1,179 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/db"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// ErrRefreshTokenReused means an already rotated refresh token was presented
+// again, which suggests it was stolen. The session is revoked.
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+type Session struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (s Session) Active() bool {
+	return s.RevokedAt == nil && time.Now().UTC().Before(s.ExpiresAt)
+}
+
+const sessionColumns = `id, user_id, user_agent, ip, created_at, last_used_at, expires_at, revoked_at`
+
+func scanSession(row interface{ Scan(...any) error }) (Session, error) {
+	var s Session
+	err := row.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, (*db.Time)(&s.CreatedAt), (*db.Time)(&s.LastUsedAt),
+		(*db.Time)(&s.ExpiresAt), db.OptionalTime(&s.RevokedAt))
+	return s, err
+}
+
+// CreateSession stores a new login session identified by the hash of its
+// refresh token.
+func (r *sqlUserRepository) CreateSession(userID int, tokenHash, userAgent, ip string, ttl time.Duration) (Session, error) {
+	now := time.Now().UTC()
+	s := Session{
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+
+	query := `
+	INSERT INTO sessions (user_id, refresh_token_hash, user_agent, ip, created_at, last_used_at, expires_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id`
+	err := r.db.QueryRow(query, userID, tokenHash, userAgent, ip, db.Time(now), db.Time(now), db.Time(s.ExpiresAt)).Scan(&s.ID)
+	if err != nil {
+		return s, fmt.Errorf("failed to create session: %w", err)
+	}
+	return s, nil
+}
+
+func (r *sqlUserRepository) GetSession(id int) (Session, error) {
+	row := r.read.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE id = ?`, id)
+	s, err := scanSession(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s, ErrSessionNotFound
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to get session: %w", err)
+	}
+	return s, nil
+}
+
+// RotateSession replaces the refresh token of the session that owns oldHash
+// and extends its lifetime. Presenting a token that was already rotated
+// revokes the session.
+func (r *sqlUserRepository) RotateSession(oldHash, newHash string, ttl time.Duration) (Session, error) {
+	row := r.db.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE refresh_token_hash = ?`, oldHash)
+	s, err := scanSession(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		row = r.db.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE previous_token_hash = ?`, oldHash)
+		if reused, err := scanSession(row); err == nil {
+			if err := r.RevokeSession(reused.ID, reused.UserID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+				return s, err
+			}
+			return s, ErrRefreshTokenReused
+		}
+		return s, ErrSessionNotFound
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to get session: %w", err)
+	}
+	if !s.Active() {
+		return s, ErrSessionNotFound
+	}
+
+	now := time.Now().UTC()
+	s.LastUsedAt = now
+	s.ExpiresAt = now.Add(ttl)
+	query := `
+	UPDATE sessions SET refresh_token_hash = ?, previous_token_hash = ?, last_used_at = ?, expires_at = ?
+	WHERE id = ? AND refresh_token_hash = ?`
+	result, err := r.db.Exec(query, newHash, oldHash, db.Time(now), db.Time(s.ExpiresAt), s.ID, oldHash)
+	if err != nil {
+		return s, fmt.Errorf("failed to rotate session: %w", err)
+	}
+	// Another request rotated the same token concurrently.
+	if n, _ := result.RowsAffected(); n == 0 {
+		return s, ErrRefreshTokenReused
+	}
+	return s, nil
+}
+
+func (r *sqlUserRepository) RevokeSession(id, userID int) error {
+	query := `UPDATE sessions SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`
+	result, err := r.db.Exec(query, db.Time(time.Now()), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// GetActiveSessions returns the user's sessions that are neither revoked
+// nor expired, most recently used first.
+func (r *sqlUserRepository) GetActiveSessions(userID int) ([]Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions
+			WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+			ORDER BY last_used_at DESC`
+	rows, err := r.read.Query(query, userID, db.Time(time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeOtherSessions revokes every session of the user except keepID.
+func (r *sqlUserRepository) RevokeOtherSessions(userID, keepID int) (int64, error) {
+	query := `UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND id != ? AND revoked_at IS NULL`
+	result, err := r.db.Exec(query, db.Time(time.Now()), userID, keepID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// TouchSession records that the session was just used from ip.
+func (r *sqlUserRepository) TouchSession(id int, ip string) error {
+	query := `UPDATE sessions SET last_used_at = ?, ip = ? WHERE id = ?`
+	_, err := r.db.Exec(query, db.Time(time.Now()), ip, id)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+// RevokeAllSessions signs the user out everywhere.
+func (r *sqlUserRepository) RevokeAllSessions(userID int) error {
+	query := `UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`
+	if _, err := r.db.Exec(query, db.Time(time.Now()), userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
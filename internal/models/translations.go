@@ -0,0 +1,36 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/db"
+)
+
+// GetCachedTranslation returns a previously stored translation, if any.
+func GetCachedTranslation(provider, source, target, text string) (string, bool, error) {
+	var result string
+	query := `SELECT result FROM translations WHERE provider = ? AND source_lang = ? AND target_lang = ? AND text = ?`
+	err := db.Read.QueryRow(query, provider, source, target, text).Scan(&result)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read cached translation: %w", err)
+	}
+	return result, true, nil
+}
+
+func SaveTranslation(provider, source, target, text, result string) error {
+	query := `
+	INSERT INTO translations (provider, source_lang, target_lang, text, result, created_at)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT (provider, source_lang, target_lang, text) DO UPDATE SET result = excluded.result`
+	_, err := db.DB.Exec(query, provider, source, target, text, result, db.Time(time.Now()))
+	if err != nil {
+		return fmt.Errorf("failed to save translation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/db"
+)
+
+// TwoFactor holds the TOTP settings of a user. A secret without Enabled is
+// an enrollment that has not been confirmed yet.
+type TwoFactor struct {
+	Secret   string
+	Enabled  bool
+	LastStep int64
+}
+
+func (r *sqlUserRepository) GetTwoFactor(userID int) (TwoFactor, error) {
+	var tf TwoFactor
+	var secret sql.NullString
+	query := `SELECT totp_secret, totp_enabled_at IS NOT NULL, COALESCE(totp_last_step, 0) FROM users WHERE id = ?`
+	if err := r.read.QueryRow(query, userID).Scan(&secret, &tf.Enabled, &tf.LastStep); err != nil {
+		return tf, fmt.Errorf("failed to read two-factor settings: %w", err)
+	}
+	tf.Secret = secret.String
+	return tf, nil
+}
+
+// SetPendingTwoFactor stores a new secret awaiting confirmation.
+func (r *sqlUserRepository) SetPendingTwoFactor(userID int, secret string) error {
+	query := `UPDATE users SET totp_secret = ?, totp_enabled_at = NULL, totp_last_step = 0 WHERE id = ?`
+	if _, err := r.db.Exec(query, secret, userID); err != nil {
+		return fmt.Errorf("failed to save two-factor secret: %w", err)
+	}
+	return nil
+}
+
+// EnableTwoFactor turns 2FA on and replaces the user's recovery codes.
+func (r *sqlUserRepository) EnableTwoFactor(userID int, step int64, recoveryCodeHashes []string) error {
+	return inTx(r.db, func(tx DBTX) error {
+		query := `UPDATE users SET totp_enabled_at = ?, totp_last_step = ? WHERE id = ?`
+		if _, err := tx.Exec(query, db.Time(time.Now()), step, userID); err != nil {
+			return fmt.Errorf("failed to enable two-factor: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		for _, hash := range recoveryCodeHashes {
+			if _, err := tx.Exec(`INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, hash); err != nil {
+				return fmt.Errorf("failed to save recovery code: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *sqlUserRepository) DisableTwoFactor(userID int) error {
+	return inTx(r.db, func(tx DBTX) error {
+		query := `UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0 WHERE id = ?`
+		if _, err := tx.Exec(query, userID); err != nil {
+			return fmt.Errorf("failed to disable two-factor: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		return nil
+	})
+}
+
+// UseTOTPStep records step as used. It returns false if this or a later
+// step was already used, which rejects replayed codes.
+func (r *sqlUserRepository) UseTOTPStep(userID int, step int64) (bool, error) {
+	query := `UPDATE users SET totp_last_step = ? WHERE id = ? AND COALESCE(totp_last_step, 0) < ?`
+	result, err := r.db.Exec(query, step, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to update two-factor step: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// UseRecoveryCode consumes an unused recovery code.
+func (r *sqlUserRepository) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	query := `UPDATE recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`
+	result, err := r.db.Exec(query, db.Time(time.Now()), userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
@@ -0,0 +1,64 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/db"
+	"github.com/Danyarbrg/flashCards/internal/db/dbtest"
+	"github.com/Danyarbrg/flashCards/internal/models"
+	"github.com/Danyarbrg/flashCards/internal/totp"
+)
+
+func TestSQLiteUseTOTPStep(t *testing.T) {
+	testUseTOTPStep(t)
+}
+
+func TestPostgresUseTOTPStep(t *testing.T) {
+	dbtest.UsePostgres(t)
+	testUseTOTPStep(t)
+}
+
+// testUseTOTPStep checks that a code is accepted once, and that codes of
+// earlier steps are refused after it.
+func testUseTOTPStep(t *testing.T) {
+	users := models.NewSQLUserRepository(db.DB, db.Read)
+	user := newUser(t, users)
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.SetPendingTwoFactor(user.ID, secret); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	code, err := totp.Code(secret, totp.Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	step, ok := totp.Verify(secret, code, now)
+	if !ok {
+		t.Fatal("code not verified")
+	}
+
+	tests := []struct {
+		name string
+		step int64
+		ok   bool
+	}{
+		{"first use", step, true},
+		{"replayed", step, false},
+		{"earlier step", step - 1, false},
+		{"next step", step + 1, true},
+	}
+	for _, tt := range tests {
+		ok, err := users.UseTOTPStep(user.ID, tt.step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/db"
+)
+
+// Purposes of single-use user tokens.
+const (
+	TokenEmailVerification = "email_verification"
+	TokenPasswordReset     = "password_reset"
+	TokenEmailChange       = "email_change"
+)
+
+var ErrInvalidUserToken = errors.New("invalid or expired token")
+
+// UserToken is a single-use, expiring token sent to the user by email.
+// Data carries purpose-specific payload.
+type UserToken struct {
+	ID        int
+	UserID    int
+	Purpose   string
+	Data      string
+	ExpiresAt time.Time
+}
+
+// CreateUserToken stores a new token and invalidates older unused tokens of
+// the same purpose, so only the most recent link works.
+func (r *sqlUserRepository) CreateUserToken(userID int, purpose, tokenHash, data string, ttl time.Duration) error {
+	now := time.Now().UTC()
+	return inTx(r.db, func(tx DBTX) error {
+		if _, err := tx.Exec(`UPDATE user_tokens SET used_at = ? WHERE user_id = ? AND purpose = ? AND used_at IS NULL`,
+			db.Time(now), userID, purpose); err != nil {
+			return fmt.Errorf("failed to invalidate old tokens: %w", err)
+		}
+
+		query := `INSERT INTO user_tokens (user_id, purpose, token_hash, data, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)`
+		if _, err := tx.Exec(query, userID, purpose, tokenHash, data, db.Time(now), db.Time(now.Add(ttl))); err != nil {
+			return fmt.Errorf("failed to create token: %w", err)
+		}
+		return nil
+	})
+}
+
+// ConsumeUserToken marks a valid token as used and returns it. A token can
+// only be consumed once.
+func (r *sqlUserRepository) ConsumeUserToken(purpose, tokenHash string) (UserToken, error) {
+	var t UserToken
+	now := db.Time(time.Now())
+
+	query := `SELECT id, user_id, purpose, data, expires_at FROM user_tokens
+			WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?`
+	err := r.db.QueryRow(query, tokenHash, purpose, now).Scan(&t.ID, &t.UserID, &t.Purpose, &t.Data, (*db.Time)(&t.ExpiresAt))
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, ErrInvalidUserToken
+	}
+	if err != nil {
+		return t, fmt.Errorf("failed to get token: %w", err)
+	}
+	result, err := r.db.Exec(`UPDATE user_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL`, now, t.ID)
+	if err != nil {
+		return t, fmt.Errorf("failed to use token: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return t, ErrInvalidUserToken
+	}
+	return t, nil
+}
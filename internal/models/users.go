@@ -1,66 +1,223 @@
 package models
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"net/mail"
+	"strings"
+	"time"
 
 	"github.com/Danyarbrg/flashCards/internal/db"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type User struct {
-	ID           int    `json:"id"`
-	Email        string `json:"email"`
-	PasswordHash string `json:"-"`
+	ID            int    `json:"id"`
+	Email         string `json:"email"`
+	PasswordHash  string `json:"-"`
+	EmailVerified bool   `json:"email_verified"`
+	Role          string `json:"role"`
+	Disabled      bool   `json:"disabled"`
 }
 
-// RegisterUser creating new users with pash password.
-func RegisterUser(email, password string) (User, error) {
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+func (u User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// userColumns are the columns read into a User, in scanUser order.
+const userColumns = `id, email, password_hash, email_verified_at IS NOT NULL, role, disabled_at IS NOT NULL`
+
+func scanUser(row interface{ Scan(...any) error }) (User, error) {
 	var user User
-	if email == "" || password == "" {
-		return user, fmt.Errorf("email and password are required")
+	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.EmailVerified, &user.Role, &user.Disabled)
+	return user, err
+}
+
+// ValidateEmail checks that email is a bare address like "name@example.com".
+func ValidateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || !strings.Contains(email[strings.LastIndex(email, "@"):], ".") {
+		return fmt.Errorf("invalid email address")
 	}
+	return nil
+}
+
+type sqlUserRepository struct {
+	db   DBTX
+	read DBTX
+}
+
+// NewSQLUserRepository returns a UserRepository that writes to db and runs
+// plain queries on read. Pass the same handle twice for a transaction.
+func NewSQLUserRepository(db, read DBTX) UserRepository {
+	return &sqlUserRepository{db: db, read: read}
+}
 
+// hashPassword validates and hashes a new password.
+func hashPassword(password string) ([]byte, error) {
+	if password == "" {
+		return nil, fmt.Errorf("password is required")
+	}
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		log.Printf("Failed to hash password: %v", err)
-		return user, fmt.Errorf("failed to hash password: %w", err)
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	return hashedPassword, nil
+}
+
+// Register creating new users with pash password.
+func (r *sqlUserRepository) Register(email, password string) (User, error) {
+	var user User
+	email = strings.TrimSpace(email)
+	if email == "" || password == "" {
+		return user, fmt.Errorf("email and password are required")
+	}
+	if err := ValidateEmail(email); err != nil {
+		return user, err
 	}
 
-	query := `INSERT INTO users (email, password_hash) VALUES (?, ?)`
-	result, err := db.DB.Exec(query, email, hashedPassword)
+	hashedPassword, err := hashPassword(password)
 	if err != nil {
-		log.Printf("Failed to register user: %v", err)
-		return user, fmt.Errorf("failed to register user: %w", err)
+		return user, err
 	}
 
-	lastID, err := result.LastInsertId()
+	// Emails are compared case-insensitively, which the UNIQUE constraint
+	// does not do.
+	query := `INSERT INTO users (email, password_hash) SELECT ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM users WHERE LOWER(email) = LOWER(?)) RETURNING id`
+	err = r.db.QueryRow(query, email, string(hashedPassword), email).Scan(&user.ID)
+	if errors.Is(err, sql.ErrNoRows) || db.IsUniqueViolation(err) {
+		return user, fmt.Errorf("failed to register user: %w", ErrEmailTaken)
+	}
 	if err != nil {
-		log.Printf("Failed to get last insert ID: %v", err)
-		return user, fmt.Errorf("failed to get last insert ID: %w", err)
+		log.Printf("Failed to register user: %v", err)
+		return user, fmt.Errorf("failed to register user: %w", err)
 	}
 
-	user.ID = int(lastID)
 	user.Email = email
 	user.PasswordHash = string(hashedPassword)
+	user.Role = RoleUser
 	return user, nil
 }
 
-// AuthenticateUser checks your email and passwd and return user in success.
-func AuthenticateUser(email, password string) (User, error) {
-	var user User
-	query := `SELECT id, email, password_hash FROM users WHERE email = ?`
-	err := db.DB.QueryRow(query, email).Scan(&user.ID, &user.Email, &user.PasswordHash)
+// Authenticate checks your email and passwd and return user in success.
+func (r *sqlUserRepository) Authenticate(email, password string) (User, error) {
+	user, err := r.GetByEmail(email)
 	if err != nil {
 		log.Printf("Failed to find user: %v", err)
 		return user, fmt.Errorf("invalid email or password")
 	}
+	return checkUserPassword(user, password)
+}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+// checkUserPassword returns user if password matches its hash.
+func checkUserPassword(user User, password string) (User, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
 		log.Printf("Invalid password: %v", err)
 		return user, fmt.Errorf("invalid email or password")
 	}
 
 	return user, nil
-}
\ No newline at end of file
+}
+
+var ErrUserNotFound = errors.New("user not found")
+
+func getUser(q DBTX, where string, arg interface{}) (User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE ` + where
+	user, err := scanUser(q.QueryRow(query, arg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, ErrUserNotFound
+	}
+	if err != nil {
+		return user, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+func (r *sqlUserRepository) GetByID(id int) (User, error) {
+	return getUser(r.read, "id = ?", id)
+}
+
+// GetByEmail finds a user by email, ignoring case. Should two accounts
+// differ only in case, the oldest is returned.
+func (r *sqlUserRepository) GetByEmail(email string) (User, error) {
+	return getUser(r.read, "LOWER(email) = LOWER(?) ORDER BY id", strings.TrimSpace(email))
+}
+
+// SetPassword replaces the user's password.
+func (r *sqlUserRepository) SetPassword(userID int, password string) error {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	if _, err := r.db.Exec(`UPDATE users SET password_hash = ? WHERE id = ?`, string(hashedPassword), userID); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlUserRepository) MarkEmailVerified(userID int) error {
+	query := `UPDATE users SET email_verified_at = ? WHERE id = ? AND email_verified_at IS NULL`
+	if _, err := r.db.Exec(query, db.Time(time.Now()), userID); err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+	return nil
+}
+
+var ErrEmailTaken = errors.New("email is already in use")
+
+// ChangeEmail sets a new, already confirmed, email address.
+func (r *sqlUserRepository) ChangeEmail(userID int, email string) error {
+	return inTx(r.db, func(tx DBTX) error {
+		query := `UPDATE users SET email = ?, email_verified_at = ? WHERE id = ?
+			AND NOT EXISTS (SELECT 1 FROM users WHERE LOWER(email) = LOWER(?) AND id <> ?)`
+		result, err := tx.Exec(query, email, db.Time(time.Now()), userID, email, userID)
+		if db.IsUniqueViolation(err) {
+			return ErrEmailTaken
+		}
+		if err != nil {
+			return fmt.Errorf("failed to change email: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			if _, err := getUser(tx, "id = ?", userID); err != nil {
+				if errors.Is(err, ErrUserNotFound) {
+					return nil
+				}
+				return err
+			}
+			return ErrEmailTaken
+		}
+		return nil
+	})
+}
+
+// userOwnedTables lists every table holding per-user data, children first.
+var userOwnedTables = []string{"card_revisions", "flashcards", "sessions", "access_tokens", "user_tokens", "recovery_codes", "user_identities"}
+
+// Delete removes the user and all of their data in one transaction.
+func (r *sqlUserRepository) Delete(userID int) error {
+	return inTx(r.db, func(tx DBTX) error {
+		for _, table := range userOwnedTables {
+			if _, err := tx.Exec(`DELETE FROM `+table+` WHERE user_id = ?`, userID); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", table, err)
+			}
+		}
+		result, err := tx.Exec(`DELETE FROM users WHERE id = ?`, userID)
+		if err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return ErrUserNotFound
+		}
+		return nil
+	})
+}
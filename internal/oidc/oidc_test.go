@@ -0,0 +1,129 @@
+package oidc_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/oidc"
+	"github.com/Danyarbrg/flashCards/internal/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newProvider(t *testing.T) (*oidc.Provider, *oidctest.Issuer) {
+	t.Helper()
+	iss, err := oidctest.NewIssuer("flashcards")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(iss.Close)
+	p := &oidc.Provider{
+		Issuer:      iss.URL,
+		ClientID:    "flashcards",
+		RedirectURL: "http://app.test/auth/oidc/callback",
+		Scopes:      []string{"openid", "email"},
+		Client:      iss.Client(),
+	}
+	return p, iss
+}
+
+// login runs the browser part of the flow and returns the code.
+func login(t *testing.T, p *oidc.Provider, iss *oidctest.Issuer, state, nonce, challenge string) string {
+	t.Helper()
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback, err := iss.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := callback.Query().Get("state"); got != state {
+		t.Fatalf("state = %q, want %q", got, state)
+	}
+	return callback.Query().Get("code")
+}
+
+func TestExchange(t *testing.T) {
+	p, iss := newProvider(t)
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := login(t, p, iss, "state-1", "nonce-1", challenge)
+
+	identity, err := p.Exchange(context.Background(), code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := oidc.Identity{Issuer: iss.URL, Subject: "user-1", Email: "user@example.com", EmailVerified: true}
+	if identity != want {
+		t.Errorf("identity = %+v, want %+v", identity, want)
+	}
+
+	// Codes can be redeemed once.
+	if _, err := p.Exchange(context.Background(), code, verifier, "nonce-1"); err == nil {
+		t.Error("second exchange of the same code succeeded")
+	}
+}
+
+func TestExchangeChecksPKCE(t *testing.T) {
+	p, iss := newProvider(t)
+	_, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := oidc.NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := login(t, p, iss, "state", "nonce", challenge)
+
+	_, err = p.Exchange(context.Background(), code, other, "nonce")
+	if err == nil || !strings.Contains(err.Error(), "PKCE") {
+		t.Errorf("exchange with the wrong verifier: err = %v", err)
+	}
+}
+
+func TestExchangeChecksNonce(t *testing.T) {
+	p, iss := newProvider(t)
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := login(t, p, iss, "state", "nonce", challenge)
+
+	_, err = p.Exchange(context.Background(), code, verifier, "another nonce")
+	if err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Errorf("exchange with the wrong nonce: err = %v", err)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	p, iss := newProvider(t)
+
+	tests := []struct {
+		name  string
+		extra jwt.MapClaims
+		ok    bool
+	}{
+		{"valid", nil, true},
+		{"other audience", jwt.MapClaims{"aud": "someone-else"}, false},
+		{"other issuer", jwt.MapClaims{"iss": "https://evil.test"}, false},
+		{"expired", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}, false},
+		{"no expiry", jwt.MapClaims{"exp": nil}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := iss.Sign("nonce", tt.extra)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = p.VerifyIDToken(context.Background(), raw, "nonce")
+			if (err == nil) != tt.ok {
+				t.Errorf("err = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
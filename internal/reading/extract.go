@@ -0,0 +1,162 @@
+package reading
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxExtracted caps the bytes decompressed from one epub, ten times the
+// upload limit of the API, so a small zip bomb cannot exhaust memory.
+const maxExtracted = 100 << 20
+
+// ExtractText returns the plain text of an uploaded .txt or .epub file.
+func ExtractText(filename string, data []byte) (string, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".txt", "":
+		if !utf8.Valid(data) {
+			return "", fmt.Errorf("text file must be UTF-8 encoded")
+		}
+		return string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))), nil
+	case ".epub":
+		return extractEpub(data)
+	default:
+		return "", fmt.Errorf("unsupported file type %q, expected .txt or .epub", path.Ext(filename))
+	}
+}
+
+func extractEpub(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("invalid epub archive: %w", err)
+	}
+
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	budget := int64(maxExtracted)
+	chapters, err := epubSpine(files, &budget)
+	if err != nil || len(chapters) == 0 {
+		// Fall back to every HTML document in archive order.
+		chapters = nil
+		for _, f := range zr.File {
+			switch strings.ToLower(path.Ext(f.Name)) {
+			case ".xhtml", ".html", ".htm":
+				chapters = append(chapters, f.Name)
+			}
+		}
+		sort.Strings(chapters)
+	}
+
+	var b strings.Builder
+	for _, name := range chapters {
+		f, ok := files[name]
+		if !ok {
+			continue
+		}
+		content, err := readZipFile(f, &budget)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		b.WriteString(htmlToText(string(content)))
+		b.WriteString("\n\n")
+	}
+	return b.String(), nil
+}
+
+// epubSpine resolves the reading order declared in the package document.
+func epubSpine(files map[string]*zip.File, budget *int64) ([]string, error) {
+	container, ok := files["META-INF/container.xml"]
+	if !ok {
+		return nil, fmt.Errorf("missing container.xml")
+	}
+	raw, err := readZipFile(container, budget)
+	if err != nil {
+		return nil, err
+	}
+	var c struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := xml.Unmarshal(raw, &c); err != nil || len(c.Rootfiles) == 0 {
+		return nil, fmt.Errorf("invalid container.xml")
+	}
+
+	opfPath := c.Rootfiles[0].FullPath
+	opf, ok := files[opfPath]
+	if !ok {
+		return nil, fmt.Errorf("missing package document %s", opfPath)
+	}
+	if raw, err = readZipFile(opf, budget); err != nil {
+		return nil, err
+	}
+	var pkg struct {
+		Items []struct {
+			ID   string `xml:"id,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"manifest>item"`
+		Refs []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"spine>itemref"`
+	}
+	if err := xml.Unmarshal(raw, &pkg); err != nil {
+		return nil, fmt.Errorf("invalid package document: %w", err)
+	}
+
+	hrefs := make(map[string]string)
+	for _, item := range pkg.Items {
+		hrefs[item.ID] = item.Href
+	}
+	dir := path.Dir(opfPath)
+	var chapters []string
+	for _, ref := range pkg.Refs {
+		if href, ok := hrefs[ref.IDRef]; ok {
+			chapters = append(chapters, path.Join(dir, href))
+		}
+	}
+	return chapters, nil
+}
+
+// readZipFile decompresses f, taking its size from budget, and fails once
+// the budget is used up.
+func readZipFile(f *zip.File, budget *int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, *budget+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > *budget {
+		return nil, fmt.Errorf("epub is larger than %d MB uncompressed", maxExtracted>>20)
+	}
+	*budget -= int64(len(data))
+	return data, nil
+}
+
+var (
+	skipBlocks = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	blockTags  = regexp.MustCompile(`(?i)</?(p|div|br|h[1-6]|li|tr|blockquote|section)[^>]*>`)
+	anyTag     = regexp.MustCompile(`<[^>]*>`)
+)
+
+func htmlToText(s string) string {
+	s = skipBlocks.ReplaceAllString(s, "")
+	s = blockTags.ReplaceAllString(s, "\n\n")
+	s = anyTag.ReplaceAllString(s, "")
+	return html.UnescapeString(s)
+}
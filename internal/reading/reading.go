@@ -0,0 +1,120 @@
+package reading
+
+import (
+	"embed"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//go:embed stopwords/*.txt
+var stopwordFiles embed.FS
+
+// Candidate is a word from a text that the user does not have a card for yet.
+type Candidate struct {
+	Word      string `json:"word"`
+	Frequency int    `json:"frequency"`
+	Sentence  string `json:"sentence"`
+}
+
+// Stopwords returns the embedded stopword list for lang, or an empty set
+// when the language is unknown.
+func Stopwords(lang string) map[string]bool {
+	set := make(map[string]bool)
+	data, err := stopwordFiles.ReadFile("stopwords/" + strings.ToLower(lang) + ".txt")
+	if err != nil {
+		return set
+	}
+	for _, w := range strings.Fields(string(data)) {
+		set[w] = true
+	}
+	return set
+}
+
+// Sentences splits text into sentences on terminal punctuation and blank lines.
+func Sentences(text string) []string {
+	var sentences []string
+	var b strings.Builder
+	runes := []rune(text)
+	flush := func() {
+		s := strings.Join(strings.Fields(b.String()), " ")
+		if s != "" {
+			sentences = append(sentences, s)
+		}
+		b.Reset()
+	}
+
+	for i, r := range runes {
+		if r == '\n' && i+1 < len(runes) && runes[i+1] == '\n' {
+			flush()
+			continue
+		}
+		b.WriteRune(r)
+		if r == '.' || r == '!' || r == '?' || r == '…' {
+			if i+1 == len(runes) || unicode.IsSpace(runes[i+1]) {
+				flush()
+			}
+		}
+	}
+	flush()
+	return sentences
+}
+
+// Words returns the lowercased words of a sentence. Apostrophes and hyphens
+// inside a word are kept ("don't", "well-known").
+func Words(sentence string) []string {
+	var words []string
+	var b strings.Builder
+	runes := []rune(sentence)
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsMark(r):
+			b.WriteRune(unicode.ToLower(r))
+		case (r == '\'' || r == '’' || r == '-') && b.Len() > 0 && i+1 < len(runes) && unicode.IsLetter(runes[i+1]):
+			if r == '’' {
+				r = '\''
+			}
+			b.WriteRune(r)
+		default:
+			if b.Len() > 0 {
+				words = append(words, b.String())
+				b.Reset()
+			}
+		}
+	}
+	if b.Len() > 0 {
+		words = append(words, b.String())
+	}
+	return words
+}
+
+// Candidates tokenizes text and returns words that are neither stopwords
+// nor in known, most frequent first. Each candidate keeps the first
+// sentence it appeared in.
+func Candidates(text string, known, stopwords map[string]bool) []Candidate {
+	byWord := make(map[string]*Candidate)
+	for _, sentence := range Sentences(text) {
+		for _, w := range Words(sentence) {
+			if len([]rune(w)) < 2 || known[w] || stopwords[w] {
+				continue
+			}
+			if c, ok := byWord[w]; ok {
+				c.Frequency++
+				continue
+			}
+			byWord[w] = &Candidate{Word: w, Frequency: 1, Sentence: sentence}
+		}
+	}
+
+	candidates := make([]Candidate, 0, len(byWord))
+	for _, c := range byWord {
+		candidates = append(candidates, *c)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Frequency != candidates[j].Frequency {
+			return candidates[i].Frequency > candidates[j].Frequency
+		}
+		return candidates[i].Word < candidates[j].Word
+	})
+	return candidates
+}
@@ -0,0 +1,146 @@
+// Package throttle slows down repeated attempts (failed logins, sign-ups)
+// with exponential backoff and temporary lockout. State is kept in memory,
+// so limits apply per server process.
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// Policy describes how quickly a key gets blocked.
+type Policy struct {
+	// FreeAttempts may fail in a row without any delay. From then on each
+	// failure blocks the key for BaseDelay, doubling each time up to
+	// MaxDelay.
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	// After LockoutAttempts failures the key is locked for LockoutDuration.
+	// Zero disables lockout.
+	LockoutAttempts int
+	LockoutDuration time.Duration
+	// Window is how long failures are remembered after the last one.
+	Window time.Duration
+}
+
+type entry struct {
+	failures     int
+	last         time.Time
+	blockedUntil time.Time
+}
+
+// Limiter tracks failures per key. A nil *Limiter allows everything.
+type Limiter struct {
+	policy  Policy
+	mu      sync.Mutex
+	entries map[string]*entry
+	now     func() time.Time
+	ops     int
+}
+
+// sweepEvery controls how often forgotten entries are removed.
+const sweepEvery = 1000
+
+func New(policy Policy) *Limiter {
+	return &Limiter{
+		policy:  policy,
+		entries: make(map[string]*entry),
+		now:     time.Now,
+	}
+}
+
+// Allow reports whether key may make an attempt now, and if not, how long
+// it has to wait.
+func (l *Limiter) Allow(key string) (time.Duration, bool) {
+	if l == nil {
+		return 0, true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	e := l.entry(key, now)
+	if e == nil || !now.Before(e.blockedUntil) {
+		return 0, true
+	}
+	return e.blockedUntil.Sub(now), false
+}
+
+// Fail records a failed attempt for key.
+func (l *Limiter) Fail(key string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	e := l.entry(key, now)
+	if e == nil {
+		e = &entry{}
+		l.entries[key] = e
+	}
+	e.failures++
+	e.last = now
+
+	p := l.policy
+	switch {
+	case p.LockoutAttempts > 0 && e.failures >= p.LockoutAttempts:
+		e.blockedUntil = now.Add(p.LockoutDuration)
+	case e.failures >= p.FreeAttempts:
+		e.blockedUntil = now.Add(backoff(p, e.failures-p.FreeAttempts+1))
+	}
+
+	l.ops++
+	if l.ops%sweepEvery == 0 {
+		l.sweep(now)
+	}
+}
+
+// Reset forgets all failures of key, e.g. after a successful login.
+func (l *Limiter) Reset(key string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+}
+
+// entry returns the state of key, dropping it once it has been forgotten.
+func (l *Limiter) entry(key string, now time.Time) *entry {
+	e, ok := l.entries[key]
+	if !ok {
+		return nil
+	}
+	if l.expired(e, now) {
+		delete(l.entries, key)
+		return nil
+	}
+	return e
+}
+
+func (l *Limiter) expired(e *entry, now time.Time) bool {
+	return now.After(e.last.Add(l.policy.Window)) && !now.Before(e.blockedUntil)
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	for key, e := range l.entries {
+		if l.expired(e, now) {
+			delete(l.entries, key)
+		}
+	}
+}
+
+// backoff returns BaseDelay * 2^(n-1), capped at MaxDelay.
+func backoff(p Policy, n int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < n && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
@@ -0,0 +1,139 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+)
+
+// clock is a fake time source for a Limiter.
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newLimiter(policy Policy) (*Limiter, *clock) {
+	c := &clock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	l := New(policy)
+	l.now = c.now
+	return l, c
+}
+
+var testPolicy = Policy{
+	FreeAttempts:    3,
+	BaseDelay:       time.Second,
+	MaxDelay:        5 * time.Second,
+	LockoutAttempts: 10,
+	LockoutDuration: time.Hour,
+	Window:          30 * time.Minute,
+}
+
+func TestFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		wait     time.Duration
+	}{
+		{"no failures", 0, 0},
+		{"within free attempts", 2, 0},
+		{"first delay", 3, time.Second},
+		{"doubled", 4, 2 * time.Second},
+		{"doubled again", 5, 4 * time.Second},
+		{"capped", 9, 5 * time.Second},
+		{"locked out", 10, time.Hour},
+		{"still locked out", 12, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, _ := newLimiter(testPolicy)
+			for i := 0; i < tt.failures; i++ {
+				l.Fail("key")
+			}
+			wait, ok := l.Allow("key")
+			if ok != (tt.wait == 0) || wait != tt.wait {
+				t.Errorf("Allow = %v, %v; want wait %v", wait, ok, tt.wait)
+			}
+			if _, ok := l.Allow("other"); !ok {
+				t.Error("another key is blocked")
+			}
+		})
+	}
+}
+
+func TestWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		after    time.Duration
+		more     int
+		wait     time.Duration
+	}{
+		// Three more failures add up to the free attempts unless the first
+		// two were forgotten.
+		{"within the window", 2, 29 * time.Minute, 3, 4 * time.Second},
+		{"after the window", 2, 31 * time.Minute, 3, time.Second},
+		// A lockout outlasts the window.
+		{"locked out past the window", 10, 45 * time.Minute, 0, 15 * time.Minute},
+		{"after the lockout", 10, 61 * time.Minute, 3, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newLimiter(testPolicy)
+			for i := 0; i < tt.failures; i++ {
+				l.Fail("key")
+			}
+			c.advance(tt.after)
+			for i := 0; i < tt.more; i++ {
+				l.Fail("key")
+			}
+			if wait, _ := l.Allow("key"); wait != tt.wait {
+				t.Errorf("wait = %v, want %v", wait, tt.wait)
+			}
+		})
+	}
+}
+
+func TestBlockExpires(t *testing.T) {
+	l, c := newLimiter(testPolicy)
+	for i := 0; i < 3; i++ {
+		l.Fail("key")
+	}
+	c.advance(999 * time.Millisecond)
+	if wait, ok := l.Allow("key"); ok || wait != time.Millisecond {
+		t.Errorf("Allow = %v, %v before the delay ended", wait, ok)
+	}
+	c.advance(time.Millisecond)
+	if _, ok := l.Allow("key"); !ok {
+		t.Error("key is blocked after the delay ended")
+	}
+}
+
+func TestReset(t *testing.T) {
+	l, _ := newLimiter(testPolicy)
+	for i := 0; i < 10; i++ {
+		l.Fail("key")
+	}
+	l.Reset("key")
+	if _, ok := l.Allow("key"); !ok {
+		t.Fatal("key is blocked after Reset")
+	}
+	l.Fail("key")
+	if _, ok := l.Allow("key"); !ok {
+		t.Error("Reset kept earlier failures")
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+	l.Fail("key")
+	l.Reset("key")
+	if _, ok := l.Allow("key"); !ok {
+		t.Error("a nil limiter blocked")
+	}
+}
@@ -0,0 +1,74 @@
+package totp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Danyarbrg/flashCards/internal/totp"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors,
+// "12345678901234567890" in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The RFC lists 8-digit codes; 6-digit codes are their last six digits.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCode(t *testing.T) {
+	for _, tt := range rfcVectors {
+		code, err := totp.Code(rfcSecret, totp.Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != tt.code {
+			t.Errorf("code at %d = %s, want %s", tt.unix, code, tt.code)
+		}
+	}
+	if _, err := totp.Code("not base32!", 1); err == nil {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	for _, tt := range rfcVectors {
+		now := time.Unix(tt.unix, 0)
+		step, ok := totp.Verify(rfcSecret, tt.code, now)
+		if !ok || step != totp.Step(now) {
+			t.Errorf("verify at %d = %d, %v", tt.unix, step, ok)
+		}
+	}
+
+	// The code is valid from the start of the step before its own to the
+	// end of the step after it.
+	now := time.Unix(1111111110, 0)
+	code := "050471"
+	tests := []struct {
+		name string
+		code string
+		at   time.Time
+		ok   bool
+	}{
+		{"one step early", code, now.Add(-30 * time.Second), true},
+		{"one step late", code, now.Add(59 * time.Second), true},
+		{"two steps early", code, now.Add(-31 * time.Second), false},
+		{"two steps late", code, now.Add(60 * time.Second), false},
+		{"with spaces", " 050 471 ", now, true},
+		{"wrong code", "050472", now, false},
+		{"too short", "50471", now, false},
+	}
+	for _, tt := range tests {
+		if _, ok := totp.Verify(rfcSecret, tt.code, tt.at); ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package translate
+
+import (
+	"context"
+	"log"
+
+	"github.com/Danyarbrg/flashCards/internal/models"
+)
+
+// Cached stores results of the wrapped translator in the translations table.
+type Cached struct {
+	Translator
+}
+
+func NewCached(t Translator) Cached {
+	return Cached{Translator: t}
+}
+
+func (c Cached) Translate(ctx context.Context, text, source, target string) (string, error) {
+	provider := c.Translator.Name()
+	if cached, ok, err := models.GetCachedTranslation(provider, source, target, text); err != nil {
+		log.Printf("Failed to read translation cache: %v", err)
+	} else if ok {
+		return cached, nil
+	}
+
+	result, err := c.Translator.Translate(ctx, text, source, target)
+	if err != nil {
+		return "", err
+	}
+	if err := models.SaveTranslation(provider, source, target, text, result); err != nil {
+		log.Printf("Failed to cache translation: %v", err)
+	}
+	return result, nil
+}
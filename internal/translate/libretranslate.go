@@ -0,0 +1,70 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LibreTranslate talks to a LibreTranslate-compatible HTTP API.
+type LibreTranslate struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+func NewLibreTranslate(url, apiKey string) *LibreTranslate {
+	return &LibreTranslate{
+		URL:    strings.TrimRight(url, "/"),
+		APIKey: apiKey,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (l *LibreTranslate) Name() string {
+	return "libretranslate"
+}
+
+func (l *LibreTranslate) Translate(ctx context.Context, text, source, target string) (string, error) {
+	body, err := json.Marshal(map[string]string{
+		"q":       text,
+		"source":  source,
+		"target":  target,
+		"format":  "text",
+		"api_key": l.APIKey,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.URL+"/translate", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("translation request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		TranslatedText string `json:"translatedText"`
+		Error          string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("invalid translation response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("translation service returned %d: %s", resp.StatusCode, result.Error)
+	}
+	if result.TranslatedText == "" {
+		return "", ErrNoTranslation
+	}
+	return result.TranslatedText, nil
+}
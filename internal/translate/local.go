@@ -0,0 +1,30 @@
+package translate
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Danyarbrg/flashCards/internal/dictionary"
+)
+
+// Local is an offline stand-in that answers single words from the loaded
+// dictionaries and leaves anything longer untranslated.
+type Local struct{}
+
+func (Local) Name() string {
+	return "local"
+}
+
+func (Local) Translate(ctx context.Context, text, source, target string) (string, error) {
+	if strings.ContainsAny(strings.TrimSpace(text), " \t\n") {
+		return "", ErrNoTranslation
+	}
+	meaning, err := dictionary.Meaning(text)
+	if err != nil {
+		return "", err
+	}
+	if meaning == "" {
+		return "", ErrNoTranslation
+	}
+	return meaning, nil
+}
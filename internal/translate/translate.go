@@ -0,0 +1,100 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Danyarbrg/flashCards/internal/dictionary"
+)
+
+// ErrNoTranslation is returned when a provider has nothing to suggest.
+var ErrNoTranslation = errors.New("no translation available")
+
+// Translator translates text between two languages. Language codes follow
+// ISO 639-1, "auto" may be used as source when the provider supports it.
+type Translator interface {
+	Name() string
+	Translate(ctx context.Context, text, source, target string) (string, error)
+}
+
+// Suggestion holds auto-filled values proposed for a new card.
+type Suggestion struct {
+	Meaning            string `json:"meaning"`
+	ExampleTranslation string `json:"example_translation,omitempty"`
+	Provider           string `json:"provider,omitempty"`
+}
+
+var (
+	active     Translator
+	sourceLang = "auto"
+	targetLang = "en"
+)
+
+// InitTranslator selects the translation provider used for suggestions.
+// Results of every provider except the local one are cached in the DB.
+func InitTranslator(provider, url, apiKey, source, target string) error {
+	if source != "" {
+		sourceLang = source
+	}
+	if target != "" {
+		targetLang = target
+	}
+
+	switch strings.ToLower(provider) {
+	case "", "local":
+		active = Local{}
+	case "libretranslate":
+		if url == "" {
+			return fmt.Errorf("TRANSLATOR_URL is required for the libretranslate provider")
+		}
+		active = NewCached(NewLibreTranslate(url, apiKey))
+	case "none":
+		active = nil
+	default:
+		return fmt.Errorf("unknown translator provider %q", provider)
+	}
+
+	if active != nil {
+		log.Printf("Using %s translator (%s -> %s).", active.Name(), sourceLang, targetLang)
+	}
+	return nil
+}
+
+// Suggest proposes a meaning for word and a translation of example. Offline
+// dictionaries are consulted first, the configured translator fills the gaps.
+func Suggest(ctx context.Context, word, example string) (Suggestion, error) {
+	var s Suggestion
+
+	if word = strings.TrimSpace(word); word != "" {
+		meaning, err := dictionary.Meaning(word)
+		if err != nil {
+			return s, fmt.Errorf("failed to look up word: %w", err)
+		}
+		if meaning != "" {
+			s.Meaning = meaning
+			s.Provider = "dictionary"
+		} else if active != nil {
+			meaning, err := active.Translate(ctx, word, sourceLang, targetLang)
+			if err != nil && !errors.Is(err, ErrNoTranslation) {
+				return s, fmt.Errorf("failed to translate word: %w", err)
+			}
+			s.Meaning = meaning
+			s.Provider = active.Name()
+		}
+	}
+
+	if example = strings.TrimSpace(example); example != "" && active != nil {
+		translated, err := active.Translate(ctx, example, sourceLang, targetLang)
+		if err != nil && !errors.Is(err, ErrNoTranslation) {
+			return s, fmt.Errorf("failed to translate example: %w", err)
+		}
+		s.ExampleTranslation = translated
+		if s.Provider == "" {
+			s.Provider = active.Name()
+		}
+	}
+	return s, nil
+}
@@ -5,10 +5,13 @@ let currentCardIndex = 0;
 let currentSortBy = 'created';
 let currentSortOrder = 'asc';
 let allUserTags = [];
+let showingTrash = false;
+let editingVersion = null;
 
-async function apiRequest(endpoint, method, body = null) {
+async function apiRequest(endpoint, method, body = null, options = {}, retried = false) {
     const headers = {
         'Content-Type': 'application/json',
+        ...options.headers,
     };
     const token = localStorage.getItem('token');
     if (token) {
@@ -27,9 +30,18 @@ async function apiRequest(endpoint, method, body = null) {
     try {
         const response = await fetch(API_URL + endpoint, config);
         if (response.status === 401) {
-            logout();
+            if (!retried && await refreshSession()) {
+                return apiRequest(endpoint, method, body, options, true);
+            }
+            clearSession();
             return;
         }
+        if (response.status === 409 && options.onConflict) {
+            return await options.onConflict(await response.json());
+        }
+        if (response.status === 412 && options.onPreconditionFailed) {
+            return await options.onPreconditionFailed(await response.json());
+        }
         if (!response.ok) {
             const errorData = await response.json();
             throw new Error(errorData.error || 'Что-то пошло не так');
@@ -44,11 +56,52 @@ async function apiRequest(endpoint, method, body = null) {
     }
 }
 
-function logout() {
+// Обмен refresh-токена на новую пару токенов
+async function refreshSession() {
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (!refreshToken) {
+        return false;
+    }
+    try {
+        const response = await fetch(API_URL + '/auth/refresh', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ refresh_token: refreshToken }),
+        });
+        if (!response.ok) {
+            return false;
+        }
+        saveSession(await response.json());
+        return true;
+    } catch (error) {
+        return false;
+    }
+}
+
+function saveSession(data) {
+    localStorage.setItem('token', data.token);
+    localStorage.setItem('refresh_token', data.refresh_token);
+}
+
+function clearSession() {
     localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
     window.location.href = '/';
 }
 
+async function logout() {
+    const token = localStorage.getItem('token');
+    if (token) {
+        try {
+            await fetch(API_URL + '/auth/logout', {
+                method: 'POST',
+                headers: { 'Authorization': `Bearer ${token}` },
+            });
+        } catch (error) {}
+    }
+    clearSession();
+}
+
 function toggleForms() {
     document.getElementById('login-form').classList.toggle('hidden');
     document.getElementById('register-form').classList.toggle('hidden');
@@ -60,8 +113,69 @@ async function login(event) {
     const password = document.getElementById('login-password').value;
     try {
         const data = await apiRequest('/login', 'POST', { email, password });
-        localStorage.setItem('token', data.token);
-        window.location.href = '/cards.html';
+        await finishLogin(data);
+    } catch (error) {}
+}
+
+async function finishLogin(data) {
+    if (data.two_factor_required) {
+        const code = prompt('Введите код из приложения-аутентификатора или код восстановления');
+        if (!code) {
+            return;
+        }
+        data = await apiRequest('/auth/2fa', 'POST', { challenge_token: data.challenge_token, code });
+    }
+    saveSession(data);
+    window.location.href = '/cards.html';
+}
+
+// Вход через внешнего провайдера возвращает токены во фрагменте URL
+async function initializeLoginPage() {
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    if (params.has('error') || params.has('token') || params.has('challenge_token')) {
+        history.replaceState(null, '', window.location.pathname);
+        if (params.has('error')) {
+            alert(params.get('error'));
+        } else {
+            try {
+                await finishLogin({
+                    token: params.get('token'),
+                    refresh_token: params.get('refresh_token'),
+                    two_factor_required: params.get('two_factor_required') === 'true',
+                    challenge_token: params.get('challenge_token'),
+                });
+            } catch (error) {}
+            return;
+        }
+    }
+    try {
+        const response = await fetch(API_URL + '/auth/providers');
+        const providers = await response.json();
+        if (providers.oidc) {
+            document.getElementById('oidc-login').classList.remove('hidden');
+        }
+    } catch (error) {}
+}
+
+async function forgotPassword() {
+    const email = document.getElementById('login-email').value || prompt('Введите email вашего аккаунта');
+    if (!email) {
+        return;
+    }
+    try {
+        const data = await apiRequest('/auth/password/forgot', 'POST', { email });
+        alert(data.message);
+    } catch (error) {}
+}
+
+async function resetPassword(event) {
+    event.preventDefault();
+    const token = new URLSearchParams(window.location.search).get('token');
+    const password = document.getElementById('reset-password').value;
+    try {
+        await apiRequest('/auth/password/reset', 'POST', { token, password });
+        alert('Пароль изменён. Теперь вы можете войти.');
+        window.location.href = '/';
     } catch (error) {}
 }
 
@@ -71,7 +185,7 @@ async function register(event) {
     const password = document.getElementById('register-password').value;
     try {
         await apiRequest('/register', 'POST', { email, password });
-        alert('Регистрация прошла успешно! Теперь вы можете войти.');
+        alert('Регистрация прошла успешно! Мы отправили письмо для подтверждения email.');
         toggleForms();
         document.getElementById('login-email').value = email;
         document.getElementById('login-password').focus();
@@ -110,9 +224,13 @@ function initializeCardsPage() {
 
         try {
             if (id) {
-                await apiRequest(`/cards/${id}`, 'PUT', cardData);
+                const saved = await apiRequest(`/cards/${id}`, 'PUT', cardData, {
+                    headers: { 'If-Match': `"${id}-${editingVersion}"` },
+                    onPreconditionFailed: (data) => showChangedCard(data.card),
+                });
+                if (!saved) return;
             } else {
-                await apiRequest('/cards', 'POST', cardData);
+                await apiRequest('/cards', 'POST', cardData, { onConflict: (data) => confirmDuplicates(data, cardData) });
             }
             modal.classList.add('hidden');
             loadCards();
@@ -120,6 +238,8 @@ function initializeCardsPage() {
         } catch (error) {}
     };
 
+    document.getElementById('card-word').addEventListener('change', suggestMeaning);
+
     document.getElementById('sort-by').addEventListener('change', (e) => {
         currentSortBy = e.target.value;
         loadCards();
@@ -144,12 +264,22 @@ function initializeCardsPage() {
         loadCards();
     });
 
+    const trashBtn = document.getElementById('trash-btn');
+    trashBtn.addEventListener('click', () => {
+        showingTrash = !showingTrash;
+        trashBtn.innerText = showingTrash ? 'Все карточки' : 'Корзина';
+        loadCards();
+    });
+
     loadCards();
     loadUserTags();
 }
 
 
 async function loadCards() {
+    if (showingTrash) {
+        return loadTrash();
+    }
     try {
         const tagFilter = document.getElementById('tag-filter').value;
         // Формируем URL с учетом фильтра
@@ -195,19 +325,59 @@ async function loadCards() {
     } catch (error) {}
 }
 
+// Похожие карточки уже есть: спрашиваем, создавать ли всё равно
+async function confirmDuplicates(data, cardData) {
+    const list = (data.duplicates || []).map(d => `${d.word} — ${d.meaning}`).join('\n');
+    if (confirm(`${data.error}\n\n${list}\n\nВсё равно создать карточку?`)) {
+        return apiRequest('/cards?force=true', 'POST', cardData);
+    }
+    throw new Error('cancelled');
+}
+
+// Подсказка значения из словарей или переводчика для новой карточки
+async function suggestMeaning() {
+    const word = document.getElementById('card-word').value.trim();
+    const meaningInput = document.getElementById('card-meaning');
+    if (!word || meaningInput.value || document.getElementById('card-id').value) {
+        return;
+    }
+    try {
+        const suggestion = await apiRequest(`/cards/suggest?word=${encodeURIComponent(word)}`, 'GET');
+        if (suggestion && suggestion.meaning && !meaningInput.value) {
+            meaningInput.value = suggestion.meaning;
+        }
+    } catch (error) {}
+}
+
 async function editCard(id) {
     try {
         const card = await apiRequest(`/cards/${id}`, 'GET');
         document.getElementById('modal-title').innerText = "Редактировать карточку";
-        document.getElementById('card-id').value = card.id;
-        document.getElementById('card-word').value = card.word;
-        document.getElementById('card-meaning').value = card.meaning;
-        document.getElementById('card-example').value = card.example;
-        document.getElementById('card-tags').value = card.tags;
+        fillCardForm(card);
         document.getElementById('card-modal').classList.remove('hidden');
     } catch (error) {}
 }
 
+function fillCardForm(card) {
+    document.getElementById('card-id').value = card.id;
+    document.getElementById('card-word').value = card.word;
+    document.getElementById('card-meaning').value = card.meaning;
+    document.getElementById('card-example').value = card.example;
+    document.getElementById('card-tags').value = card.tags;
+    editingVersion = card.version;
+}
+
+// Карточку успели изменить в другой вкладке или на другом устройстве
+function showChangedCard(card) {
+    if (!card) {
+        alert('Карточка была удалена.');
+        return null;
+    }
+    alert('Карточку уже изменили в другом месте. Форма обновлена — проверьте и сохраните снова.');
+    fillCardForm(card);
+    return null;
+}
+
 async function loadUserTags() {
     try {
         allUserTags = await apiRequest('/cards/tags', 'GET') || [];
@@ -224,14 +394,51 @@ async function loadUserTags() {
 }
 
 async function deleteCard(id) {
-    if (confirm('Вы уверены, что хотите удалить эту карточку?')) {
+    if (confirm('Переместить карточку в корзину?')) {
         try {
             await apiRequest(`/cards/${id}`, 'DELETE');
             loadCards();
+            loadUserTags();
         } catch (error) {}
     }
 }
 
+// Удалённые карточки хранятся в корзине, пока их не очистят автоматически
+async function loadTrash() {
+    try {
+        const cards = await apiRequest('/cards/trash', 'GET');
+        const container = document.getElementById('cards-container');
+        container.innerHTML = '';
+        if (cards && cards.length > 0) {
+            cards.forEach(card => {
+                const cardElement = document.createElement('div');
+                cardElement.className = 'card';
+                cardElement.innerHTML = `
+                    <div>
+                        <h3>${card.word}</h3>
+                        <p>${card.meaning}</p>
+                        <small>Удалена ${new Date(card.deleted_at).toLocaleString()}</small>
+                    </div>
+                    <div class="card-actions">
+                        <button class="restore-btn" onclick="restoreCard(${card.id})">Восстановить</button>
+                    </div>
+                `;
+                container.appendChild(cardElement);
+            });
+        } else {
+            container.innerHTML = '<p>Корзина пуста.</p>';
+        }
+    } catch (error) {}
+}
+
+async function restoreCard(id) {
+    try {
+        await apiRequest(`/cards/trash/${id}/restore`, 'POST');
+        loadCards();
+        loadUserTags();
+    } catch (error) {}
+}
+
 function initializeReviewPage() {
     const flashcard = document.querySelector('.flashcard');
     